@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/provider"
+	"logs-aggregator-bot/services"
+	"logs-aggregator-bot/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	shutdownTimeout = 10 * time.Second
+	maxRequestBody  = 1 << 20
+)
+
+type Server struct {
+	handler    *services.ApiHandler
+	provider   provider.StorageProvider
+	httpServer *http.Server
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type settingsResponse struct {
+	UserId        int64                      `json:"userId"`
+	UserName      string                     `json:"userName"`
+	Role          string                     `json:"role"`
+	WorkStarted   time.Time                  `json:"workStarted"`
+	WorkFinished  time.Time                  `json:"workFinished"`
+	Projects      []string                   `json:"projects"`
+	Tags          []string                   `json:"tags"`
+	IssuePatterns []string                   `json:"issuePatterns"`
+	Schedule      models.ScheduleSettingsDto `json:"schedule"`
+}
+
+type reportTotalResponse struct {
+	Name    string `json:"name"`
+	Seconds int64  `json:"seconds"`
+}
+
+type reportResponse struct {
+	From         string                `json:"from"`
+	To           string                `json:"to"`
+	TotalSeconds int64                 `json:"totalSeconds"`
+	Days         []reportTotalResponse `json:"days"`
+	Tasks        []reportTotalResponse `json:"tasks"`
+	Projects     []reportTotalResponse `json:"projects"`
+	Tags         []reportTotalResponse `json:"tags"`
+	Issues       []reportTotalResponse `json:"issues"`
+}
+
+func NewServer(addr string, handler *services.ApiHandler, provider provider.StorageProvider) *Server {
+	s := &Server{handler: handler, provider: provider}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/dates", s.authorized(s.getDates))
+	mux.HandleFunc("GET /api/v1/logs", s.authorized(s.getLogs))
+	mux.HandleFunc("POST /api/v1/logs", s.authorized(s.createLog))
+	mux.HandleFunc("PUT /api/v1/logs/{date}/{id}", s.authorized(s.updateLog))
+	mux.HandleFunc("DELETE /api/v1/logs/{date}", s.authorized(s.deleteLogs))
+	mux.HandleFunc("DELETE /api/v1/logs/{date}/{id}", s.authorized(s.deleteLog))
+	mux.HandleFunc("GET /api/v1/settings", s.authorized(s.getSettings))
+	mux.HandleFunc("PUT /api/v1/settings/schedule", s.authorized(s.updateSchedule))
+	mux.HandleFunc("GET /api/v1/reports", s.authorized(s.getReport))
+
+	s.httpServer = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	return s
+}
+
+// Start serves requests until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := s.httpServer.Shutdown(shutdownCtx)
+
+		if err != nil {
+			logrus.Errorf("Failed to shutdown api server: %v", err)
+		}
+	}()
+
+	logrus.Infof("Api server listens on %s", s.httpServer.Addr)
+
+	err := s.httpServer.ListenAndServe()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *Server) authorized(next func(w http.ResponseWriter, r *http.Request, userId int64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if !found {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		settings, err := s.handler.AuthenticateApiToken(strings.TrimSpace(token))
+
+		if errors.Is(err, services.ErrInvalidApiToken) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if err != nil {
+			s.writeInternalError(w, err)
+			return
+		}
+
+		next(w, r, settings.UserId)
+	}
+}
+
+func (s *Server) getDates(w http.ResponseWriter, r *http.Request, userId int64) {
+	dates, err := s.provider.GetDatesWithLogs(userId)
+
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+
+	if dates == nil {
+		dates = []string{}
+	}
+
+	writeJson(w, http.StatusOK, dates)
+}
+
+func (s *Server) getLogs(w http.ResponseWriter, r *http.Request, userId int64) {
+	date := time.Now()
+
+	if r.URL.Query().Has("date") {
+		var ok bool
+
+		date, ok = parseDateParam(w, r.URL.Query().Get("date"))
+
+		if !ok {
+			return
+		}
+	}
+
+	logs, err := s.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+
+	if logs == nil {
+		logs = []models.LogsInfoDto{}
+	}
+
+	writeJson(w, http.StatusOK, logs)
+}
+
+func (s *Server) createLog(w http.ResponseWriter, r *http.Request, userId int64) {
+	var log models.LogsInfoDto
+
+	if !readJson(w, r, &log) {
+		return
+	}
+
+	err := s.handler.CreateLog(userId, &log)
+
+	if !s.writeLogError(w, err) {
+		writeJson(w, http.StatusCreated, log)
+	}
+}
+
+func (s *Server) updateLog(w http.ResponseWriter, r *http.Request, userId int64) {
+	date, ok := parseDateParam(w, r.PathValue("date"))
+
+	if !ok {
+		return
+	}
+
+	var log models.LogsInfoDto
+
+	if !readJson(w, r, &log) {
+		return
+	}
+
+	log.Id = r.PathValue("id")
+	err := s.handler.UpdateLog(userId, date, &log)
+
+	if !s.writeLogError(w, err) {
+		writeJson(w, http.StatusOK, log)
+	}
+}
+
+func (s *Server) deleteLogs(w http.ResponseWriter, r *http.Request, userId int64) {
+	date, ok := parseDateParam(w, r.PathValue("date"))
+
+	if !ok {
+		return
+	}
+
+	err := s.handler.TrashLogsByDate(userId, utils.GetOnlyDate(date))
+
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteLog(w http.ResponseWriter, r *http.Request, userId int64) {
+	date, ok := parseDateParam(w, r.PathValue("date"))
+
+	if !ok {
+		return
+	}
+
+	_, err := s.handler.DeleteLog(userId, date, r.PathValue("id"))
+
+	if !s.writeLogError(w, err) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) getSettings(w http.ResponseWriter, r *http.Request, userId int64) {
+	settings, err := s.provider.GetUserSettings(userId)
+
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toSettingsResponse(settings))
+}
+
+func (s *Server) updateSchedule(w http.ResponseWriter, r *http.Request, userId int64) {
+	var schedule models.ScheduleSettingsDto
+
+	if !readJson(w, r, &schedule) {
+		return
+	}
+
+	settings, err := s.handler.UpdateSchedule(userId, schedule)
+
+	if errors.Is(err, services.ErrInvalidSchedule) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toSettingsResponse(settings))
+}
+
+func (s *Server) getReport(w http.ResponseWriter, r *http.Request, userId int64) {
+	from, ok := parseDateParam(w, r.URL.Query().Get("from"))
+
+	if !ok {
+		return
+	}
+
+	to, ok := parseDateParam(w, r.URL.Query().Get("to"))
+
+	if !ok {
+		return
+	}
+
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+
+	report, err := s.handler.BuildReport(userId, from, to)
+
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, reportResponse{
+		From:         utils.GetOnlyDate(report.From),
+		To:           utils.GetOnlyDate(report.To),
+		TotalSeconds: int64(report.Total.Seconds()),
+		Days:         toReportTotalsResponse(report.Days),
+		Tasks:        toReportTotalsResponse(report.Tasks),
+		Projects:     toReportTotalsResponse(report.Projects),
+		Tags:         toReportTotalsResponse(report.Tags),
+		Issues:       toReportTotalsResponse(report.Issues),
+	})
+}
+
+// writeLogError maps log validation errors to client errors. It returns false
+// when there is no error.
+func (s *Server) writeLogError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrLogNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidLog):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.writeInternalError(w, err)
+	}
+
+	return true
+}
+
+func (s *Server) writeInternalError(w http.ResponseWriter, err error) {
+	logrus.Errorf("Api request failed: %v", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+func parseDateParam(w http.ResponseWriter, value string) (time.Time, bool) {
+	date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be in format YYYY-MM-DD")
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+func readJson(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+
+	if err != nil {
+		logrus.Errorf("Failed to write api response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, errorResponse{Error: message})
+}
+
+func toSettingsResponse(settings *models.UserSettingsDto) settingsResponse {
+	return settingsResponse{
+		UserId:        settings.UserId,
+		UserName:      settings.UserName,
+		Role:          string(settings.Role),
+		WorkStarted:   settings.WorkStarted,
+		WorkFinished:  settings.WorkFinished,
+		Projects:      settings.Projects,
+		Tags:          settings.Tags,
+		IssuePatterns: settings.IssuePatterns,
+		Schedule:      settings.Schedule,
+	}
+}
+
+func toReportTotalsResponse(totals []models.ReportTotalDto) []reportTotalResponse {
+	result := make([]reportTotalResponse, 0, len(totals))
+
+	for _, v := range totals {
+		result = append(result, reportTotalResponse{Name: v.Name, Seconds: int64(v.Duration.Seconds())})
+	}
+
+	return result
+}
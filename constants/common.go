@@ -4,6 +4,22 @@ const (
 	CallbackParamContinueOldLog = "continue_old_log"
 	CallbackParamCreateNewLog   = "create_new_log"
 	CallbackStopDeleteLogs      = "stop_delete_log"
+	CallbackParamNoProject      = "no_project"
+	CallbackParamTagsDone       = "tags_done"
+	CallbackParamUseSuggestion  = "use_suggestion"
+	CallbackParamKeepMessage    = "keep_message"
+	CallbackParamEditMessage    = "edit_message"
+	CallbackParamEditStart      = "edit_start"
+	CallbackParamEditEnd        = "edit_end"
+	CallbackParamCancel         = "cancel"
+	CallbackParamConfirm        = "confirm"
+	CallbackPrefixUndoDelete    = "undo_delete:"
+	CallbackPrefixProject       = "project:"
+	CallbackPrefixTag           = "tag:"
+	CallbackPrefixPage          = "page:"
+	CallbackPrefixCalendar      = "calendar:"
+	CallbackPrefixTime          = "time:"
+	CallbackParamIgnore         = "ignore"
 )
 
 type UserState int
@@ -16,14 +32,89 @@ const (
 	UserStateSelectNewLogMessage
 	UserStateSelectLogDate
 	UserStateSelectLogsToDelete
+	UserStateSelectProject
+	UserStateSelectTags
+	UserStateSelectIssueSuggestion
+	UserStateSelectEditLog
+	UserStateSelectEditField
+	UserStateEnterEditValue
+	UserStateSelectDeleteLog
+	UserStateConfirmDeleteLog
+	UserStateConfirmDeleteLogs
+	UserStateSelectRestoreDate
+	UserStateSelectReportFrom
+	UserStateSelectReportTo
+	UserStateConfirmQuickLog
 )
 
 type Commands string
 
 const (
-	StartWorkDayCommand Commands = "start_work_day"
-	EndWorkDayCommand   Commands = "end_work_day"
-	GetLogsCommand      Commands = "get_today_logs"
-	GetAllLogsCommand   Commands = "get_all_logs"
-	DeleteLogsCommand   Commands = "delete_logs_command"
+	StartWorkDayCommand  Commands = "start_work_day"
+	EndWorkDayCommand    Commands = "end_work_day"
+	GetLogsCommand       Commands = "get_today_logs"
+	GetAllLogsCommand    Commands = "get_all_logs"
+	DeleteLogsCommand    Commands = "delete_logs_command"
+	StartCommand         Commands = "start"
+	InviteCommand        Commands = "invite"
+	ApproveUserCommand   Commands = "approve"
+	BlockUserCommand     Commands = "block"
+	ListUsersCommand     Commands = "users"
+	SettingsCommand      Commands = "settings"
+	SetIntervalCommand   Commands = "set_interval"
+	SetWorkHoursCommand  Commands = "set_work_hours"
+	SetWorkDaysCommand   Commands = "set_work_days"
+	SetLunchCommand      Commands = "set_lunch"
+	SetCronCommand       Commands = "set_cron"
+	SetAutoStopCommand   Commands = "set_auto_stop"
+	SetTargetCommand     Commands = "set_target"
+	SetTimeStepCommand   Commands = "set_time_step"
+	ReportWeekCommand    Commands = "report_week"
+	ReportMonthCommand   Commands = "report_month"
+	ReportCommand        Commands = "report"
+	EditLogCommand       Commands = "edit"
+	DeleteLogCommand     Commands = "delete_log"
+	TrashCommand         Commands = "trash"
+	RestoreCommand       Commands = "restore"
+	ProjectsCommand      Commands = "projects"
+	TagsCommand          Commands = "tags"
+	IssuePatternsCommand Commands = "issue_patterns"
+	JiraAuthCommand      Commands = "jira_auth"
+	JiraSyncCommand      Commands = "jira_sync"
+	ApiTokenCommand      Commands = "api_token"
+	ExportCommand        Commands = "export"
+	ExportXlsxCommand    Commands = "export_xlsx"
+)
+
+type UserRole string
+
+const (
+	UserRoleAdmin UserRole = "admin"
+	UserRoleUser  UserRole = "user"
+)
+
+type UserStatus string
+
+const (
+	UserStatusActive  UserStatus = "active"
+	UserStatusPending UserStatus = "pending"
+	UserStatusBlocked UserStatus = "blocked"
+)
+
+type SyncStatus string
+
+const (
+	SyncStatusPending SyncStatus = "pending"
+	SyncStatusSynced  SyncStatus = "synced"
+	SyncStatusFailed  SyncStatus = "failed"
+)
+
+type WebhookEventType string
+
+const (
+	WebhookEventLogCreated      WebhookEventType = "log.created"
+	WebhookEventLogUpdated      WebhookEventType = "log.updated"
+	WebhookEventLogDeleted      WebhookEventType = "log.deleted"
+	WebhookEventWorkDayStarted  WebhookEventType = "workday.started"
+	WebhookEventWorkDayFinished WebhookEventType = "workday.finished"
 )
@@ -0,0 +1,160 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	worklogPath    = "/rest/api/2/issue/%s/worklog"
+	worklogIdPath  = "/rest/api/2/issue/%s/worklog/%s"
+	startedFormat  = "2006-01-02T15:04:05.000-0700"
+	maxErrorLength = 512
+)
+
+type Credentials struct {
+	Email string
+	Token string
+}
+
+type Worklog struct {
+	Started   time.Time
+	TimeSpent time.Duration
+	Comment   string
+}
+
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jira responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// NotFound reports whether the issue or the worklog does not exist.
+func (e *Error) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Retryable reports whether repeating the request may succeed.
+func (e *Error) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+}
+
+type worklogRequest struct {
+	Started          string `json:"started"`
+	TimeSpentSeconds int64  `json:"timeSpentSeconds"`
+	Comment          string `json:"comment"`
+}
+
+type worklogResponse struct {
+	Id string `json:"id"`
+}
+
+func NewClient(baseUrl string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Client{baseUrl: strings.TrimRight(baseUrl, "/"), httpClient: httpClient}
+}
+
+func (c *Client) AddWorklog(ctx context.Context, credentials Credentials, issueKey string, worklog Worklog) (string, error) {
+	var response worklogResponse
+
+	err := c.do(ctx, credentials, http.MethodPost, fmt.Sprintf(worklogPath, url.PathEscape(issueKey)), &worklog, &response)
+
+	if err != nil {
+		return "", err
+	}
+
+	return response.Id, nil
+}
+
+func (c *Client) UpdateWorklog(ctx context.Context, credentials Credentials, issueKey string, worklogId string, worklog Worklog) error {
+	return c.do(ctx, credentials, http.MethodPut, fmt.Sprintf(worklogIdPath, url.PathEscape(issueKey), url.PathEscape(worklogId)), &worklog, nil)
+}
+
+func (c *Client) DeleteWorklog(ctx context.Context, credentials Credentials, issueKey string, worklogId string) error {
+	return c.do(ctx, credentials, http.MethodDelete, fmt.Sprintf(worklogIdPath, url.PathEscape(issueKey), url.PathEscape(worklogId)), nil, nil)
+}
+
+// do sends the worklog, if any, and decodes the response into response.
+func (c *Client) do(ctx context.Context, credentials Credentials, method string, path string, worklog *Worklog, response any) error {
+	var body io.Reader
+
+	if worklog != nil {
+		data, err := json.Marshal(worklogRequest{
+			Started:          worklog.Started.Format(startedFormat),
+			TimeSpentSeconds: int64(worklog.TimeSpent.Seconds()),
+			Comment:          worklog.Comment,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, body)
+
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if credentials.Email != "" {
+		req.SetBasicAuth(credentials.Email, credentials.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+credentials.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text := string(respBody)
+
+		if len(text) > maxErrorLength {
+			text = text[:maxErrorLength]
+		}
+
+		return &Error{StatusCode: resp.StatusCode, Body: text}
+	}
+
+	if response == nil || len(respBody) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(respBody, response)
+}
@@ -0,0 +1,156 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientRequests(t *testing.T) {
+	started := time.Date(2024, 5, 13, 10, 0, 0, 0, time.FixedZone("", 3*60*60))
+	worklog := Worklog{Started: started, TimeSpent: 90 * time.Minute, Comment: "PROJ-1 review"}
+
+	tests := []struct {
+		name        string
+		credentials Credentials
+		call        func(c *Client, credentials Credentials) (string, error)
+		method      string
+		path        string
+		withBody    bool
+		response    string
+		id          string
+	}{
+		{
+			name:        "add",
+			credentials: Credentials{Email: "user@example.com", Token: "secret"},
+			call: func(c *Client, credentials Credentials) (string, error) {
+				return c.AddWorklog(context.Background(), credentials, "PROJ-1", worklog)
+			},
+			method:   http.MethodPost,
+			path:     "/rest/api/2/issue/PROJ-1/worklog",
+			withBody: true,
+			response: `{"id":"100"}`,
+			id:       "100",
+		},
+		{
+			name:        "update",
+			credentials: Credentials{Token: "pat"},
+			call: func(c *Client, credentials Credentials) (string, error) {
+				return "", c.UpdateWorklog(context.Background(), credentials, "PROJ-1", "100", worklog)
+			},
+			method:   http.MethodPut,
+			path:     "/rest/api/2/issue/PROJ-1/worklog/100",
+			withBody: true,
+		},
+		{
+			name:        "delete",
+			credentials: Credentials{Token: "pat"},
+			call: func(c *Client, credentials Credentials) (string, error) {
+				return "", c.DeleteWorklog(context.Background(), credentials, "PROJ-1", "100")
+			},
+			method: http.MethodDelete,
+			path:   "/rest/api/2/issue/PROJ-1/worklog/100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tt.method || r.URL.Path != tt.path {
+					t.Errorf("got %s %s, want %s %s", r.Method, r.URL.Path, tt.method, tt.path)
+				}
+
+				if email, token, ok := r.BasicAuth(); tt.credentials.Email != "" && (!ok || email != tt.credentials.Email || token != tt.credentials.Token) {
+					t.Errorf("got basic auth %q %q, want %q %q", email, token, tt.credentials.Email, tt.credentials.Token)
+				}
+
+				if tt.credentials.Email == "" && r.Header.Get("Authorization") != "Bearer "+tt.credentials.Token {
+					t.Errorf("got authorization %q", r.Header.Get("Authorization"))
+				}
+
+				body, err := io.ReadAll(r.Body)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !tt.withBody {
+					if len(body) != 0 {
+						t.Errorf("got body %s, want none", body)
+					}
+
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+
+				var request worklogRequest
+
+				err = json.Unmarshal(body, &request)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := worklogRequest{Started: "2024-05-13T10:00:00.000+0300", TimeSpentSeconds: 5400, Comment: "PROJ-1 review"}
+
+				if request != want {
+					t.Errorf("got request %+v, want %+v", request, want)
+				}
+
+				_, _ = io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			id, err := tt.call(NewClient(server.URL+"/", nil), tt.credentials)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id != tt.id {
+				t.Errorf("got id %q, want %q", id, tt.id)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+		notFound  bool
+	}{
+		{status: http.StatusBadRequest},
+		{status: http.StatusUnauthorized},
+		{status: http.StatusNotFound, notFound: true},
+		{status: http.StatusTooManyRequests, retryable: true},
+		{status: http.StatusServiceUnavailable, retryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, `{"errorMessages":["failed"]}`)
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL, nil).AddWorklog(context.Background(), Credentials{Token: "pat"}, "PROJ-1", Worklog{})
+
+			var jiraErr *Error
+
+			if !errors.As(err, &jiraErr) {
+				t.Fatalf("got error %v, want *Error", err)
+			}
+
+			if jiraErr.StatusCode != tt.status || jiraErr.Retryable() != tt.retryable || jiraErr.NotFound() != tt.notFound {
+				t.Errorf("got status %d retryable %v not found %v", jiraErr.StatusCode, jiraErr.Retryable(), jiraErr.NotFound())
+			}
+		})
+	}
+}
@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/api"
 	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/jira"
 	"logs-aggregator-bot/models"
 	"logs-aggregator-bot/provider"
 	"logs-aggregator-bot/services"
 	"logs-aggregator-bot/tg"
+	"logs-aggregator-bot/webhook"
 	"os"
 	"strconv"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -29,16 +35,49 @@ func main() {
 
 	tgClient := tg.NewTgClient(tgBot)
 
-	scheduler := services.NewSchedulerService(storageProvider, tgClient)
-	handler := services.NewApiHandler(storageProvider, tgClient, scheduler)
+	webhooks := newWebhookService(storageProvider)
 
-	tgHandler := tg.NewTgHandler(tgBot, handler, storageProvider)
+	if webhooks != nil {
+		go webhooks.Run(context.TODO())
+	}
+
+	scheduler := services.NewSchedulerService(storageProvider, tgClient, webhooks)
+
+	err = scheduler.Resume(context.TODO())
+
+	if err != nil {
+		panic(err)
+	}
+	jiraSync := newJiraSyncService(storageProvider, tgClient)
+	handler := services.NewApiHandler(storageProvider, tgClient, scheduler, jiraSync, webhooks, tgBot.Self.UserName)
+
+	err = handler.ResumeLogDeletions()
+
+	if err != nil {
+		panic(err)
+	}
+
+	go handler.RunTrashPurge(context.TODO())
+
+	if apiAddr := os.Getenv("API_ADDR"); apiAddr != "" {
+		apiServer := api.NewServer(apiAddr, handler, storageProvider)
+
+		go func() {
+			err := apiServer.Start(context.TODO())
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	tgHandler := tg.NewTgHandler(tgBot, tgClient, handler, storageProvider)
 
 	tgHandler.Start(context.TODO())
 }
 
-func initStorage() *provider.JsonStorageProvider {
-	storageProvider, err := provider.NewJsonStorageProvider()
+func initStorage() provider.StorageProvider {
+	storageProvider, err := newStorageProvider(os.Getenv("STORAGE_TYPE"))
 
 	if err != nil {
 		panic(err)
@@ -56,17 +95,32 @@ func initStorage() *provider.JsonStorageProvider {
 		panic("SUPER_USER_ID is not a number")
 	}
 
-	currentSettings, err := storageProvider.GetUserSettings()
+	err = ensureUser(storageProvider, longSuperUser, constants.UserRoleAdmin)
 
 	if err != nil {
 		panic(err)
 	}
 
-	if currentSettings.UserId != longSuperUser {
-		err = storageProvider.SetUserSettings(&models.UserSettingsDto{
-			UserId:       longSuperUser,
-			CurrentState: constants.UserStateNone,
-		})
+	err = demoteFormerAdmins(storageProvider, longSuperUser)
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, allowedUser := range strings.Split(os.Getenv("ALLOWED_USER_IDS"), ",") {
+		allowedUser = strings.TrimSpace(allowedUser)
+
+		if allowedUser == "" {
+			continue
+		}
+
+		longAllowedUser, err := strconv.ParseInt(allowedUser, 10, 64)
+
+		if err != nil {
+			panic("ALLOWED_USER_IDS must be a comma separated list of numbers")
+		}
+
+		err = ensureUser(storageProvider, longAllowedUser, constants.UserRoleUser)
 
 		if err != nil {
 			panic(err)
@@ -75,3 +129,104 @@ func initStorage() *provider.JsonStorageProvider {
 
 	return storageProvider
 }
+
+func demoteFormerAdmins(storageProvider provider.StorageProvider, superUserId int64) error {
+	users, err := storageProvider.GetUsers()
+
+	if err != nil {
+		return err
+	}
+
+	for _, v := range users {
+		if v.UserId == superUserId || !v.IsAdmin() {
+			continue
+		}
+
+		_, err = storageProvider.UpdateUserSettings(v.UserId, func(settings *models.UserSettingsDto) error {
+			settings.Role = constants.UserRoleUser
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ensureUser(storageProvider provider.StorageProvider, userId int64, role constants.UserRole) error {
+	_, err := storageProvider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		if role == constants.UserRoleAdmin {
+			settings.Role = role
+			settings.Status = constants.UserStatusActive
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, provider.ErrUserNotFound) {
+		return err
+	}
+
+	return storageProvider.SetUserSettings(&models.UserSettingsDto{
+		UserId:       userId,
+		Role:         role,
+		Status:       constants.UserStatusActive,
+		CurrentState: constants.UserStateNone,
+	})
+}
+
+func newStorageProvider(storageType string) (provider.StorageProvider, error) {
+	switch storageType {
+	case "", provider.StorageTypeJson:
+		return provider.NewJsonStorageProvider()
+	case provider.StorageTypeSqlite:
+		path := os.Getenv("SQLITE_PATH")
+
+		if path == "" {
+			path = provider.DefaultSqliteFile
+		}
+
+		return provider.NewSqliteStorageProvider(path)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_TYPE: %s", storageType)
+	}
+}
+
+// newJiraSyncService returns nil when the Jira integration is not configured.
+func newJiraSyncService(storageProvider provider.StorageProvider, tgClient *tg.TgClient) *services.JiraSyncService {
+	baseUrl := os.Getenv("JIRA_BASE_URL")
+
+	if baseUrl == "" {
+		return nil
+	}
+
+	return services.NewJiraSyncService(storageProvider, jira.NewClient(baseUrl, nil), tgClient)
+}
+
+// newWebhookService returns nil when no WEBHOOK_URLS are configured. The events
+// are always signed, so WEBHOOK_SECRET is required with them.
+func newWebhookService(storageProvider provider.StorageProvider) *services.WebhookService {
+	var urls []string
+
+	for _, url := range strings.Split(os.Getenv("WEBHOOK_URLS"), ",") {
+		url = strings.TrimSpace(url)
+
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil
+	}
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+
+	if secret == "" {
+		panic("not passed WEBHOOK_SECRET for WEBHOOK_URLS")
+	}
+
+	return services.NewWebhookService(storageProvider, webhook.NewClient(secret, nil), urls)
+}
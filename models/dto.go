@@ -1,10 +1,41 @@
 package models
 
+import "time"
+
 type SendNotificationRequest struct {
-	ChatId        int64
-	Body          string
-	Markup        []MarkupData
+	ChatId int64
+	Body   string
+	Markup []MarkupData
+	// Controls are shown under Markup on every page of a long keyboard.
+	Controls      []MarkupData
+	Columns       int
 	IsMultiSelect bool
+	// Calendar replaces Markup with a month grid, the days are sent back as
+	// dates in the YYYY-MM-DD format.
+	Calendar *CalendarDto
+	// TimePicker replaces Markup with an hour and then minute selection, the
+	// time is sent back as unix milliseconds.
+	TimePicker *TimePickerDto
+}
+
+type TimePickerDto struct {
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+type CalendarDto struct {
+	Month       time.Time
+	MarkedDates []string
+	// OnlyMarked leaves the days without marks unselectable.
+	OnlyMarked bool
+}
+
+type SendDocumentRequest struct {
+	ChatId   int64
+	FileName string
+	Data     []byte
+	Caption  string
 }
 
 type MarkupData struct {
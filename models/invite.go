@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type InviteDto struct {
+	Code      string    `json:"code"`
+	CreatedBy int64     `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	UsedBy    int64     `json:"usedBy"`
+	UsedAt    time.Time `json:"usedAt"`
+}
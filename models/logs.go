@@ -1,14 +1,39 @@
 package models
 
-import "time"
+import (
+	"logs-aggregator-bot/constants"
+	"time"
+)
 
 type LogsInfoDto struct {
-	Id            string    `json:"id"`
-	StartWorkTime time.Time `json:"startWorkTime"`
-	EndWorkTime   time.Time `json:"endWorkTime"`
-	Message       string    `json:"message"`
+	Id            string               `json:"id"`
+	StartWorkTime time.Time            `json:"startWorkTime"`
+	EndWorkTime   time.Time            `json:"endWorkTime"`
+	Message       string               `json:"message"`
+	Project       string               `json:"project"`
+	Tags          []string             `json:"tags"`
+	IssueKey      string               `json:"issueKey"`
+	JiraWorklogId string               `json:"jiraWorklogId"`
+	JiraIssueKey  string               `json:"jiraIssueKey"`
+	SyncStatus    constants.SyncStatus `json:"syncStatus"`
+	SyncError     string               `json:"syncError"`
 }
 
 type LogsNavigationDto struct {
-	Date map[string]string `json:"date"`
+	Date  map[string]string             `json:"date"`
+	Trash map[string]TrashedLogsFileDto `json:"trash"`
+	// Issues maps the issue keys to the day of their latest log, nil until the
+	// index is built.
+	Issues map[string]string `json:"issues"`
+}
+
+type TrashedLogsFileDto struct {
+	FileName  string    `json:"fileName"`
+	TrashedAt time.Time `json:"trashedAt"`
+}
+
+type TrashedDayDto struct {
+	Date      string
+	LogsCount int
+	TrashedAt time.Time
 }
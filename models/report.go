@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type ReportDto struct {
+	From     time.Time
+	To       time.Time
+	Total    time.Duration
+	Days     []ReportTotalDto
+	Tasks    []ReportTotalDto
+	Projects []ReportTotalDto
+	Tags     []ReportTotalDto
+	Issues   []ReportTotalDto
+}
+
+type ReportTotalDto struct {
+	Name     string
+	Duration time.Duration
+}
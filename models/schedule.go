@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type ScheduleSettingsDto struct {
+	ReminderInterval time.Duration  `json:"reminderInterval"`
+	Cron             string         `json:"cron"`
+	WorkHours        *TimeRangeDto  `json:"workHours"`
+	LunchBreak       *TimeRangeDto  `json:"lunchBreak"`
+	WorkDays         []time.Weekday `json:"workDays"`
+	AutoStopTime     string         `json:"autoStopTime"`
+	DailyTarget      time.Duration  `json:"dailyTarget"`
+	TimeStep         time.Duration  `json:"timeStep"`
+}
+
+type TimeRangeDto struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
@@ -6,8 +6,48 @@ import (
 )
 
 type UserSettingsDto struct {
-	UserId        int64
-	WorkStarted   time.Time
-	CurrentState  constants.UserState
-	NeedWorkLogTo time.Time
+	UserId         int64
+	Role           constants.UserRole
+	Status         constants.UserStatus
+	UserName       string
+	WorkStarted    time.Time
+	WorkFinished   time.Time
+	LastReminderAt time.Time
+	CurrentState   constants.UserState
+	NeedWorkLogTo  time.Time
+	PendingMessage string
+	PendingProject string
+	PendingTags    []string
+	PendingHint    string
+	PendingLog     *LogsInfoDto
+	EditLogId      string
+	EditLogDate    string
+	EditField      string
+	DeleteLogId    string
+	DeleteLogDate  string
+	LogDeletions   []LogDeletionDto
+	ReportFrom     string
+	Projects       []string
+	Tags           []string
+	IssuePatterns  []string
+	JiraEmail      string
+	JiraToken      string
+	ApiTokenHash   string
+	SummaryPending bool
+	Schedule       ScheduleSettingsDto
+}
+
+func (u *UserSettingsDto) IsAdmin() bool {
+	return u.Role == constants.UserRoleAdmin
+}
+
+func (u *UserSettingsDto) IsActive() bool {
+	return u.Status == "" || u.Status == constants.UserStatusActive
+}
+
+// LogDeletionDto is a confirmed deletion that can still be undone until DeleteAt.
+type LogDeletionDto struct {
+	LogId    string
+	Date     string
+	DeleteAt time.Time
 }
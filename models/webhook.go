@@ -0,0 +1,32 @@
+package models
+
+import (
+	"logs-aggregator-bot/constants"
+	"time"
+)
+
+type WebhookEventDto struct {
+	Id        string                     `json:"id"`
+	Type      constants.WebhookEventType `json:"type"`
+	UserId    int64                      `json:"userId"`
+	CreatedAt time.Time                  `json:"createdAt"`
+	Log       *LogsInfoDto               `json:"log,omitempty"`
+	Date      string                     `json:"date,omitempty"`
+	WorkDay   *WorkDayDto                `json:"workDay,omitempty"`
+}
+
+type WorkDayDto struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+type WebhookDeliveryDto struct {
+	Id            string                     `json:"id"`
+	EventType     constants.WebhookEventType `json:"eventType"`
+	Url           string                     `json:"url"`
+	Payload       string                     `json:"payload"`
+	Attempts      int                        `json:"attempts"`
+	NextAttemptAt time.Time                  `json:"nextAttemptAt"`
+	LastError     string                     `json:"lastError"`
+	CreatedAt     time.Time                  `json:"createdAt"`
+}
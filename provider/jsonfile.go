@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+const backupFileSuffix = ".bak"
+
+func ensureJsonFile(path string, defaultValue any) error {
+	if _, err := os.Stat(path); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if _, err := os.Stat(path + backupFileSuffix); err == nil {
+		logrus.Warnf("File %s is missing, restoring it from backup", path)
+		return restoreFromBackup(path)
+	}
+
+	return writeJsonFile(path, defaultValue)
+}
+
+func readJsonFile(path string, value any) error {
+	content, err := os.ReadFile(path)
+
+	if err == nil {
+		err = json.Unmarshal(content, value)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	logrus.Warnf("File %s is corrupted (%v), falling back to the last good copy", path, err)
+
+	backupContent, backupErr := os.ReadFile(path + backupFileSuffix)
+
+	if backupErr != nil {
+		return fmt.Errorf("read %s: %w, no backup available: %v", path, err, backupErr)
+	}
+
+	backupErr = json.Unmarshal(backupContent, value)
+
+	if backupErr != nil {
+		return fmt.Errorf("read %s: %w, backup is corrupted too: %v", path, err, backupErr)
+	}
+
+	return writeFileAtomic(path, backupContent)
+}
+
+func writeJsonFile(path string, value any) error {
+	content, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(path, content)
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path+backupFileSuffix, content)
+}
+
+func removeJsonFile(path string) error {
+	err := os.Remove(path)
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err = os.Remove(path + backupFileSuffix)
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func restoreFromBackup(path string) error {
+	content, err := os.ReadFile(path + backupFileSuffix)
+
+	if err != nil {
+		return err
+	}
+
+	if !json.Valid(content) {
+		return fmt.Errorf("backup of %s is corrupted", path)
+	}
+
+	return writeFileAtomic(path, content)
+}
+
+func writeFileAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+
+	tmpFile, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+
+	if err != nil {
+		return err
+	}
+
+	tmpName := tmpFile.Name()
+	defer os.Remove(tmpName)
+
+	_, err = tmpFile.Write(content)
+
+	if err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Sync()
+
+	if err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpName, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpName, path)
+
+	if err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type jsonFileTestValue struct {
+	Name string `json:"name"`
+}
+
+func TestReadJsonFileFallsBackToBackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary []byte
+	}{
+		{name: "truncated", primary: []byte(`{"name":"ne`)},
+		{name: "corrupted", primary: []byte("\x00\x00\x00")},
+		{name: "empty", primary: []byte{}},
+		{name: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "value.json")
+
+			err := writeJsonFile(path, jsonFileTestValue{Name: "good"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.primary == nil {
+				err = os.Remove(path)
+			} else {
+				err = os.WriteFile(path, tt.primary, 0644)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var value jsonFileTestValue
+
+			err = readJsonFile(path, &value)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if value.Name != "good" {
+				t.Errorf("got %q, want the backup value", value.Name)
+			}
+
+			content, err := os.ReadFile(path)
+
+			if err != nil || string(content) != `{"name":"good"}` {
+				t.Errorf("got primary file %q error %v, want it restored", content, err)
+			}
+		})
+	}
+}
+
+func TestReadJsonFileWithoutGoodCopy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value.json")
+
+	for _, v := range []string{path, path + backupFileSuffix} {
+		err := os.WriteFile(v, []byte(`{"name":`), 0644)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var value jsonFileTestValue
+
+	if err := readJsonFile(path, &value); err == nil {
+		t.Error("expected an error when the backup is corrupted too")
+	}
+}
+
+func TestEnsureJsonFileRestoresBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value.json")
+
+	err := writeJsonFile(path, jsonFileTestValue{Name: "good"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Remove(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ensureJsonFile(path, jsonFileTestValue{Name: "default"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value jsonFileTestValue
+
+	err = readJsonFile(path, &value)
+
+	if err != nil || value.Name != "good" {
+		t.Errorf("got %q error %v, want the backup value over the default", value.Name, err)
+	}
+}
+
+func TestFailedWriteKeepsPreviousContents(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, dir string)
+		value   any
+	}{
+		{
+			name:  "value json cannot encode",
+			value: map[string]any{"name": make(chan int)},
+		},
+		{
+			name: "read-only directory",
+			prepare: func(t *testing.T, dir string) {
+				if os.Geteuid() == 0 {
+					t.Skip("directory permissions are not enforced for root")
+				}
+
+				err := os.Chmod(dir, 0555)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				t.Cleanup(func() {
+					_ = os.Chmod(dir, 0755)
+				})
+			},
+			value: jsonFileTestValue{Name: "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "value.json")
+
+			err := writeJsonFile(path, jsonFileTestValue{Name: "good"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.prepare != nil {
+				tt.prepare(t, dir)
+			}
+
+			if err = writeJsonFile(path, tt.value); err == nil {
+				t.Fatal("expected the write to fail")
+			}
+
+			var value jsonFileTestValue
+
+			err = readJsonFile(path, &value)
+
+			if err != nil || value.Name != "good" {
+				t.Errorf("got %q error %v, want the previous value", value.Name, err)
+			}
+
+			entries, err := os.ReadDir(dir)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != 2 {
+				t.Errorf("got %d files, want the file and its backup only", len(entries))
+			}
+		})
+	}
+}
+
+func TestInterruptedWriteKeepsPreviousContents(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "value.json")
+
+	err := writeJsonFile(path, jsonFileTestValue{Name: "good"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash during the write leaves a partial temporary file behind, the
+	// file itself is only replaced by the final rename.
+	err = os.WriteFile(filepath.Join(dir, "value.json.tmp-1"), []byte(`{"name":"ne`), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value jsonFileTestValue
+
+	err = readJsonFile(path, &value)
+
+	if err != nil || value.Name != "good" {
+		t.Errorf("got %q error %v, want the previous value", value.Name, err)
+	}
+
+	err = writeJsonFile(path, jsonFileTestValue{Name: "new"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = readJsonFile(path, &value)
+
+	if err != nil || value.Name != "new" {
+		t.Errorf("got %q error %v, want the new value", value.Name, err)
+	}
+}
@@ -1,99 +1,157 @@
 package provider
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"logs-aggregator-bot/models"
 	"logs-aggregator-bot/utils"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
-	logFileNavigationFile = "logs_navigation.json"
-	logFilePatternFile    = "logs_%s.json"
-	userSettingsFile      = "user.json"
+	logFileNavigationFile  = "logs_navigation.json"
+	logFilePatternFile     = "logs_%s.json"
+	trashFilePatternFile   = "trash_logs_%s.json"
+	usersSettingsFile      = "users.json"
+	invitesFile            = "invites.json"
+	webhooksFile           = "webhooks.json"
+	userLogsDirPattern     = "logs/%d"
+	legacyUserSettingsFile = "user.json"
 )
 
 type JsonStorageProvider struct {
+	mu sync.Mutex
 }
 
 func NewJsonStorageProvider() (*JsonStorageProvider, error) {
-	content := []byte("{}")
-	if _, err := os.Stat(userSettingsFile); errors.Is(err, os.ErrNotExist) {
-		_, err = os.Create(userSettingsFile)
+	j := &JsonStorageProvider{}
 
-		if err != nil {
-			return nil, err
-		}
+	err := j.migrateLegacyLayout()
 
-		err = os.WriteFile(userSettingsFile, content, 0644)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	err = ensureJsonFile(usersSettingsFile, map[int64]*models.UserSettingsDto{})
+
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := os.Stat(logFileNavigationFile); errors.Is(err, os.ErrNotExist) {
-		_, err = os.Create(logFileNavigationFile)
+	err = ensureJsonFile(invitesFile, map[string]*models.InviteDto{})
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		err = os.WriteFile(logFileNavigationFile, content, 0644)
+	err = ensureJsonFile(webhooksFile, map[string]*models.WebhookDeliveryDto{})
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	return &JsonStorageProvider{}, nil
+	return j, nil
 }
 
-func (j *JsonStorageProvider) GetUserSettings() (*models.UserSettingsDto, error) {
-	content, err := os.ReadFile(userSettingsFile)
+func (j *JsonStorageProvider) GetUsers() ([]models.UserSettingsDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	users, err := j.readUsers()
 
 	if err != nil {
 		return nil, err
 	}
 
-	var settings *models.UserSettingsDto
+	result := make([]models.UserSettingsDto, 0, len(users))
+
+	for _, v := range users {
+		result = append(result, *v)
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].UserId < result[k].UserId
+	})
+
+	return result, nil
+}
+
+func (j *JsonStorageProvider) GetUserSettings(userId int64) (*models.UserSettingsDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
-	err = json.Unmarshal(content, &settings)
+	users, err := j.readUsers()
 
 	if err != nil {
 		return nil, err
 	}
 
+	settings, exist := users[userId]
+
+	if !exist {
+		return nil, ErrUserNotFound
+	}
+
 	return settings, nil
 }
 
 func (j *JsonStorageProvider) SetUserSettings(dto *models.UserSettingsDto) error {
-	data, err := json.Marshal(dto)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	users, err := j.readUsers()
 
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(userSettingsFile, data, 0644)
+	users[dto.UserId] = dto
+
+	return writeJsonFile(usersSettingsFile, users)
+}
+
+func (j *JsonStorageProvider) UpdateUserSettings(userId int64, update func(settings *models.UserSettingsDto) error) (*models.UserSettingsDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	users, err := j.readUsers()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
-}
+	settings, exist := users[userId]
+
+	if !exist {
+		return nil, ErrUserNotFound
+	}
 
-func (j *JsonStorageProvider) InsertNewLogRecord(date time.Time, log *models.LogsInfoDto) error {
-	logFile, err := j.getLogFileByDate(date)
+	err = update(settings)
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = writeJsonFile(usersSettingsFile, users)
+
+	if err != nil {
+		return nil, err
 	}
 
-	logContent, err := os.ReadFile(logFile)
+	return settings, nil
+}
+
+func (j *JsonStorageProvider) InsertNewLogRecord(userId int64, date time.Time, log *models.LogsInfoDto) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	logFile, err := j.getLogFileByDate(userId, date)
 
 	if err != nil {
 		return err
@@ -101,223 +159,932 @@ func (j *JsonStorageProvider) InsertNewLogRecord(date time.Time, log *models.Log
 
 	var logData []models.LogsInfoDto
 
-	err = json.Unmarshal(logContent, &logData)
+	err = readJsonFile(logFile, &logData)
 
 	if err != nil {
 		return err
 	}
+
 	logData = append(logData, *log)
 
-	logContent, err = json.Marshal(logData)
+	err = writeJsonFile(logFile, logData)
+
+	if err != nil {
+		return err
+	}
+
+	return j.updateIssuesIndex(userId, utils.GetOnlyDate(date), *log)
+}
+
+func (j *JsonStorageProvider) UpdateLogRecord(userId int64, log *models.LogsInfoDto) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := j.updateLogInFiles(userId, log, func(stored *models.LogsInfoDto) {
+		stored.StartWorkTime = log.StartWorkTime
+		stored.EndWorkTime = log.EndWorkTime
+		stored.Message = log.Message
+		stored.Project = log.Project
+		stored.Tags = log.Tags
+		stored.IssueKey = log.IssueKey
+	})
 
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(logFile, logContent, 0644)
+	return j.updateIssuesIndex(userId, utils.GetOnlyDate(log.EndWorkTime), *log)
+}
+
+func (j *JsonStorageProvider) UpdateLogSyncStatus(userId int64, log *models.LogsInfoDto) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.updateLogInFiles(userId, log, func(stored *models.LogsInfoDto) {
+		stored.JiraWorklogId = log.JiraWorklogId
+		stored.JiraIssueKey = log.JiraIssueKey
+		stored.SyncStatus = log.SyncStatus
+		stored.SyncError = log.SyncError
+	})
+}
+
+// updateLogInFiles applies the update to the log stored in the day file of
+// its start or end date, entries crossing midnight live in the latter.
+func (j *JsonStorageProvider) updateLogInFiles(userId int64, log *models.LogsInfoDto, update func(stored *models.LogsInfoDto)) error {
+	navigationDto, err := j.readNavigation(userId)
 
 	if err != nil {
 		return err
 	}
 
+	for _, date := range []time.Time{log.StartWorkTime, log.EndWorkTime} {
+		fileName, exist := navigationDto.Date[utils.GetOnlyDate(date)]
+
+		if !exist {
+			continue
+		}
+
+		logFile := filepath.Join(j.getUserLogsDir(userId), fileName)
+
+		var logData []*models.LogsInfoDto
+
+		err = readJsonFile(logFile, &logData)
+
+		if err != nil {
+			return err
+		}
+
+		for _, v := range logData {
+			if v.Id == log.Id {
+				update(v)
+				return writeJsonFile(logFile, logData)
+			}
+		}
+	}
+
 	return nil
 }
 
-func (j *JsonStorageProvider) UpdateLogRecord(log *models.LogsInfoDto) error {
-	logFile, err := j.getLogFileByDate(log.StartWorkTime)
+func (j *JsonStorageProvider) GetLogRecords(userId int64, date time.Time) ([]models.LogsInfoDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	logFile, exist, err := j.findLogFileByDate(userId, date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	logData := make([]models.LogsInfoDto, 0)
+
+	if !exist {
+		return logData, nil
+	}
+
+	err = readJsonFile(logFile, &logData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return logData, nil
+}
+
+func (j *JsonStorageProvider) GetLastLogByIssueKey(userId int64, issueKey string) (*models.LogsInfoDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	navigationDto, err := j.readNavigation(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if navigationDto.Issues == nil {
+		err = j.buildIssuesIndex(userId, navigationDto)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	date, exist := navigationDto.Issues[issueKey]
+
+	if !exist {
+		return nil, nil
+	}
+
+	lastLog, err := j.findLastLogOfIssue(userId, navigationDto, date, issueKey)
+
+	if err != nil || lastLog != nil {
+		return lastLog, err
+	}
+
+	// The indexed log was deleted or moved to the trash since, the rebuilt index
+	// points to an older one.
+	err = j.buildIssuesIndex(userId, navigationDto)
+
+	if err != nil {
+		return nil, err
+	}
+
+	date, exist = navigationDto.Issues[issueKey]
+
+	if !exist {
+		return nil, nil
+	}
+
+	return j.findLastLogOfIssue(userId, navigationDto, date, issueKey)
+}
+
+func (j *JsonStorageProvider) findLastLogOfIssue(userId int64, navigationDto *models.LogsNavigationDto, date string, issueKey string) (*models.LogsInfoDto, error) {
+	fileName, exist := navigationDto.Date[date]
+
+	if !exist {
+		return nil, nil
+	}
+
+	var logData []models.LogsInfoDto
+
+	logFile := filepath.Join(j.getUserLogsDir(userId), fileName)
+
+	err := ensureJsonFile(logFile, []models.LogsInfoDto{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = readJsonFile(logFile, &logData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var lastLog *models.LogsInfoDto
+
+	for i, v := range logData {
+		if v.IssueKey == issueKey && (lastLog == nil || v.EndWorkTime.After(lastLog.EndWorkTime)) {
+			lastLog = &logData[i]
+		}
+	}
+
+	return lastLog, nil
+}
+
+// buildIssuesIndex reads every day once, afterwards the index is kept up to
+// date by the inserts and updates.
+func (j *JsonStorageProvider) buildIssuesIndex(userId int64, navigationDto *models.LogsNavigationDto) error {
+	navigationDto.Issues = map[string]string{}
+
+	for date, fileName := range navigationDto.Date {
+		var logData []models.LogsInfoDto
+
+		logFile := filepath.Join(j.getUserLogsDir(userId), fileName)
+
+		err := ensureJsonFile(logFile, []models.LogsInfoDto{})
+
+		if err != nil {
+			return err
+		}
+
+		err = readJsonFile(logFile, &logData)
+
+		if err != nil {
+			return err
+		}
+
+		indexIssueKeys(navigationDto, date, logData...)
+	}
+
+	return writeJsonFile(j.getNavigationFile(userId), navigationDto)
+}
+
+func (j *JsonStorageProvider) updateIssuesIndex(userId int64, date string, logs ...models.LogsInfoDto) error {
+	navigationDto, err := j.readNavigation(userId)
 
 	if err != nil {
 		return err
 	}
 
-	logContent, err := os.ReadFile(logFile)
+	if !indexIssueKeys(navigationDto, date, logs...) {
+		return nil
+	}
+
+	return writeJsonFile(j.getNavigationFile(userId), navigationDto)
+}
+
+// indexIssueKeys records the date for the issues of the logs unless a later day
+// is known, nothing is recorded before the index is built.
+func indexIssueKeys(navigationDto *models.LogsNavigationDto, date string, logs ...models.LogsInfoDto) bool {
+	if navigationDto.Issues == nil {
+		return false
+	}
+
+	changed := false
+
+	for _, v := range logs {
+		if v.IssueKey != "" && navigationDto.Issues[v.IssueKey] < date {
+			navigationDto.Issues[v.IssueKey] = date
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func (j *JsonStorageProvider) GetDatesWithLogs(userId int64) ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	navigationDto, err := j.readNavigation(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, 0)
+	for s, _ := range navigationDto.Date {
+		dates = append(dates, s)
+	}
+
+	sort.Strings(dates)
+
+	return dates, nil
+}
+
+func (j *JsonStorageProvider) DeleteLogsByDate(userId int64, date string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	navigationDto, err := j.readNavigation(userId)
+
+	if err != nil {
+		return err
+	}
+
+	fileName, exist := navigationDto.Date[date]
+
+	if !exist {
+		return nil
+	}
+
+	delete(navigationDto.Date, date)
+
+	err = writeJsonFile(j.getNavigationFile(userId), navigationDto)
 
 	if err != nil {
 		return err
 	}
 
-	var logData []*models.LogsInfoDto
+	return removeJsonFile(filepath.Join(j.getUserLogsDir(userId), fileName))
+}
+
+// DeleteLogRecord removes a single log of the date, the day is dropped from
+// the navigation once its last log is gone.
+func (j *JsonStorageProvider) DeleteLogRecord(userId int64, date string, logId string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
-	err = json.Unmarshal(logContent, &logData)
+	navigationDto, err := j.readNavigation(userId)
 
 	if err != nil {
 		return err
 	}
 
+	fileName, exist := navigationDto.Date[date]
+
+	if !exist {
+		return nil
+	}
+
+	logFile := filepath.Join(j.getUserLogsDir(userId), fileName)
+
+	var logData []models.LogsInfoDto
+
+	err = readJsonFile(logFile, &logData)
+
+	if err != nil {
+		return err
+	}
+
+	var rest []models.LogsInfoDto
+
 	for _, v := range logData {
-		if log.Id == v.Id {
-			v.EndWorkTime = log.EndWorkTime
+		if v.Id != logId {
+			rest = append(rest, v)
 		}
 	}
 
-	logContent, err = json.Marshal(logData)
+	if len(rest) == len(logData) {
+		return nil
+	}
+
+	if len(rest) > 0 {
+		return writeJsonFile(logFile, rest)
+	}
+
+	delete(navigationDto.Date, date)
+
+	err = writeJsonFile(j.getNavigationFile(userId), navigationDto)
 
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(logFile, logContent, 0644)
+	return removeJsonFile(logFile)
+}
+
+// TrashLogsByDate moves the day file into the trash. A day trashed again
+// before the purge is merged into the logs already in the trash.
+func (j *JsonStorageProvider) TrashLogsByDate(userId int64, date string, trashedAt time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	navigationDto, err := j.readNavigation(userId)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	fileName, exist := navigationDto.Date[date]
+
+	if !exist {
+		return nil
+	}
+
+	userDir := j.getUserLogsDir(userId)
+
+	var logData []models.LogsInfoDto
+
+	err = readJsonFile(filepath.Join(userDir, fileName), &logData)
+
+	if err != nil {
+		return err
+	}
+
+	trashed, exist := navigationDto.Trash[date]
+
+	if exist {
+		var trashedData []models.LogsInfoDto
+
+		err = readJsonFile(filepath.Join(userDir, trashed.FileName), &trashedData)
+
+		if err != nil {
+			return err
+		}
+
+		logData = append(trashedData, logData...)
+	} else {
+		trashed.FileName = fmt.Sprintf(trashFilePatternFile, date)
+	}
+
+	err = writeJsonFile(filepath.Join(userDir, trashed.FileName), logData)
+
+	if err != nil {
+		return err
+	}
+
+	trashed.TrashedAt = trashedAt
+	navigationDto.Trash[date] = trashed
+	delete(navigationDto.Date, date)
+
+	err = writeJsonFile(j.getNavigationFile(userId), navigationDto)
+
+	if err != nil {
+		return err
+	}
+
+	return removeJsonFile(filepath.Join(userDir, fileName))
 }
 
-func (j *JsonStorageProvider) GetLogRecords(date time.Time) ([]models.LogsInfoDto, error) {
-	logFile, err := j.getLogFileByDate(date)
+func (j *JsonStorageProvider) GetTrashedDays(userId int64) ([]models.TrashedDayDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	navigationDto, err := j.readNavigation(userId)
 
 	if err != nil {
 		return nil, err
 	}
 
-	logContent, err := os.ReadFile(logFile)
+	days := make([]models.TrashedDayDto, 0, len(navigationDto.Trash))
+
+	for date, trashed := range navigationDto.Trash {
+		var logData []models.LogsInfoDto
+
+		err = readJsonFile(filepath.Join(j.getUserLogsDir(userId), trashed.FileName), &logData)
+
+		if err != nil {
+			return nil, err
+		}
+
+		days = append(days, models.TrashedDayDto{Date: date, LogsCount: len(logData), TrashedAt: trashed.TrashedAt})
+	}
+
+	sort.Slice(days, func(i, k int) bool {
+		return days[i].Date < days[k].Date
+	})
+
+	return days, nil
+}
+
+// RestoreLogsByDate brings the day back from the trash, merging it with the
+// logs recorded for the date since. Nothing is restored for an unknown date.
+func (j *JsonStorageProvider) RestoreLogsByDate(userId int64, date string) ([]models.LogsInfoDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	navigationDto, err := j.readNavigation(userId)
 
 	if err != nil {
 		return nil, err
 	}
 
-	var logData []models.LogsInfoDto
+	trashed, exist := navigationDto.Trash[date]
+
+	if !exist {
+		return nil, nil
+	}
+
+	userDir := j.getUserLogsDir(userId)
 
-	err = json.Unmarshal(logContent, &logData)
+	var trashedData []models.LogsInfoDto
+
+	err = readJsonFile(filepath.Join(userDir, trashed.FileName), &trashedData)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return logData, nil
-}
+	logData := trashedData
+	fileName, exist := navigationDto.Date[date]
+
+	if exist {
+		var current []models.LogsInfoDto
+
+		err = readJsonFile(filepath.Join(userDir, fileName), &current)
+
+		if err != nil {
+			return nil, err
+		}
+
+		logData = current
+
+		for _, v := range trashedData {
+			if !containsLog(current, v.Id) {
+				logData = append(logData, v)
+			}
+		}
+	} else {
+		fileName = fmt.Sprintf(logFilePatternFile, date)
+	}
 
-func (j *JsonStorageProvider) GetDatesWithLogs() ([]string, error) {
-	content, err := os.ReadFile(logFileNavigationFile)
+	err = writeJsonFile(filepath.Join(userDir, fileName), logData)
 
 	if err != nil {
 		return nil, err
 	}
 
-	var navigationDto models.LogsNavigationDto
-	err = json.Unmarshal(content, &navigationDto)
+	navigationDto.Date[date] = fileName
+	delete(navigationDto.Trash, date)
+	indexIssueKeys(navigationDto, date, trashedData...)
+
+	err = writeJsonFile(j.getNavigationFile(userId), navigationDto)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if navigationDto.Date == nil {
-		return []string{}, nil
+	return trashedData, removeJsonFile(filepath.Join(userDir, trashed.FileName))
+}
+
+func (j *JsonStorageProvider) PurgeTrash(trashedBefore time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	users, err := j.readUsers()
+
+	if err != nil {
+		return err
 	}
 
-	dates := make([]string, 0)
-	for s, _ := range navigationDto.Date {
-		dates = append(dates, s)
+	for userId := range users {
+		navigationDto, err := j.readNavigation(userId)
+
+		if err != nil {
+			return err
+		}
+
+		purged := false
+
+		for date, trashed := range navigationDto.Trash {
+			if !trashed.TrashedAt.Before(trashedBefore) {
+				continue
+			}
+
+			err = removeJsonFile(filepath.Join(j.getUserLogsDir(userId), trashed.FileName))
+
+			if err != nil {
+				return err
+			}
+
+			delete(navigationDto.Trash, date)
+			purged = true
+		}
+
+		if !purged {
+			continue
+		}
+
+		err = writeJsonFile(j.getNavigationFile(userId), navigationDto)
+
+		if err != nil {
+			return err
+		}
 	}
 
-	return dates, nil
+	return nil
 }
 
-func (j *JsonStorageProvider) DeleteLogsByDate(date string) error {
-	content, err := os.ReadFile(logFileNavigationFile)
+func (j *JsonStorageProvider) CreateInvite(invite *models.InviteDto) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	invites, err := j.readInvites()
 
 	if err != nil {
 		return err
 	}
 
-	var navigationDto models.LogsNavigationDto
-	err = json.Unmarshal(content, &navigationDto)
+	invites[invite.Code] = invite
+
+	return writeJsonFile(invitesFile, invites)
+}
+
+func (j *JsonStorageProvider) UseInvite(code string, userId int64, usedAt time.Time) (*models.InviteDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	invites, err := j.readInvites()
+
+	if err != nil {
+		return nil, err
+	}
+
+	invite, exist := invites[code]
+
+	if !exist || invite.UsedBy != 0 || usedAt.After(invite.ExpiresAt) {
+		return nil, ErrInviteNotFound
+	}
+
+	invite.UsedBy = userId
+	invite.UsedAt = usedAt
+
+	err = writeJsonFile(invitesFile, invites)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (j *JsonStorageProvider) EnqueueWebhookDeliveries(deliveries []*models.WebhookDeliveryDto) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stored, err := j.readWebhookDeliveries()
+
 	if err != nil {
 		return err
 	}
 
-	if navigationDto.Date == nil {
-		return nil
+	for _, v := range deliveries {
+		stored[v.Id] = v
 	}
 
-	fileName, exist := navigationDto.Date[date]
+	return writeJsonFile(webhooksFile, stored)
+}
 
-	if !exist {
-		return nil
+func (j *JsonStorageProvider) GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDeliveryDto, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stored, err := j.readWebhookDeliveries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.WebhookDeliveryDto
+
+	for _, v := range stored {
+		if !v.NextAttemptAt.After(now) {
+			result = append(result, *v)
+		}
 	}
 
-	err = os.Remove(fileName)
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].NextAttemptAt.Before(result[k].NextAttemptAt)
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (j *JsonStorageProvider) UpdateWebhookDelivery(delivery *models.WebhookDeliveryDto) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stored, err := j.readWebhookDeliveries()
 
 	if err != nil {
 		return err
 	}
 
-	delete(navigationDto.Date, date)
+	if _, exist := stored[delivery.Id]; !exist {
+		return nil
+	}
+
+	stored[delivery.Id] = delivery
+
+	return writeJsonFile(webhooksFile, stored)
+}
 
-	content, err = json.Marshal(navigationDto)
+func (j *JsonStorageProvider) DeleteWebhookDelivery(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stored, err := j.readWebhookDeliveries()
 
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(logFileNavigationFile, content, 0644)
+	delete(stored, id)
+
+	return writeJsonFile(webhooksFile, stored)
 }
 
-func (j *JsonStorageProvider) getLogFileByDate(date time.Time) (string, error) {
-	content, err := os.ReadFile(logFileNavigationFile)
+func (j *JsonStorageProvider) readWebhookDeliveries() (map[string]*models.WebhookDeliveryDto, error) {
+	var deliveries map[string]*models.WebhookDeliveryDto
+
+	err := readJsonFile(webhooksFile, &deliveries)
 
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if deliveries == nil {
+		deliveries = map[string]*models.WebhookDeliveryDto{}
+	}
+
+	return deliveries, nil
+}
+
+func (j *JsonStorageProvider) readInvites() (map[string]*models.InviteDto, error) {
+	var invites map[string]*models.InviteDto
+
+	err := readJsonFile(invitesFile, &invites)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if invites == nil {
+		invites = map[string]*models.InviteDto{}
+	}
+
+	return invites, nil
+}
+
+func (j *JsonStorageProvider) readUsers() (map[int64]*models.UserSettingsDto, error) {
+	var users map[int64]*models.UserSettingsDto
+
+	err := readJsonFile(usersSettingsFile, &users)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if users == nil {
+		users = map[int64]*models.UserSettingsDto{}
+	}
+
+	return users, nil
+}
+
+func (j *JsonStorageProvider) readNavigation(userId int64) (*models.LogsNavigationDto, error) {
+	navigationFile := j.getNavigationFile(userId)
+
+	err := os.MkdirAll(j.getUserLogsDir(userId), 0755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = ensureJsonFile(navigationFile, models.LogsNavigationDto{})
+
+	if err != nil {
+		return nil, err
 	}
 
 	var navigationDto *models.LogsNavigationDto
-	err = json.Unmarshal(content, &navigationDto)
+
+	err = readJsonFile(navigationFile, &navigationDto)
 
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if navigationDto == nil {
+		navigationDto = &models.LogsNavigationDto{}
 	}
 
 	if navigationDto.Date == nil {
 		navigationDto.Date = map[string]string{}
 	}
 
+	if navigationDto.Trash == nil {
+		navigationDto.Trash = map[string]models.TrashedLogsFileDto{}
+	}
+
+	return navigationDto, nil
+}
+
+func (j *JsonStorageProvider) getUserLogsDir(userId int64) string {
+	return fmt.Sprintf(userLogsDirPattern, userId)
+}
+
+func (j *JsonStorageProvider) getNavigationFile(userId int64) string {
+	return filepath.Join(j.getUserLogsDir(userId), logFileNavigationFile)
+}
+
+// findLogFileByDate returns the day file of the date, a day without logs has
+// none.
+func (j *JsonStorageProvider) findLogFileByDate(userId int64, date time.Time) (string, bool, error) {
+	navigationDto, err := j.readNavigation(userId)
+
+	if err != nil {
+		return "", false, err
+	}
+
 	fileName, exist := navigationDto.Date[utils.GetOnlyDate(date)]
 
 	if !exist {
-		fileName = fmt.Sprintf(logFilePatternFile, utils.GetOnlyDate(date))
+		return "", false, nil
+	}
 
-		navigationDto.Date[utils.GetOnlyDate(date)] = fileName
-		content, err = json.Marshal(navigationDto)
+	logFile := filepath.Join(j.getUserLogsDir(userId), fileName)
+	return logFile, true, ensureJsonFile(logFile, []models.LogsInfoDto{})
+}
 
-		if err != nil {
-			return "", err
-		}
+// getLogFileByDate returns the day file of the date, creating it for the
+// first log of the day.
+func (j *JsonStorageProvider) getLogFileByDate(userId int64, date time.Time) (string, error) {
+	navigationDto, err := j.readNavigation(userId)
+
+	if err != nil {
+		return "", err
+	}
+
+	userDir := j.getUserLogsDir(userId)
+	fileName, exist := navigationDto.Date[utils.GetOnlyDate(date)]
+
+	if exist {
+		return filepath.Join(userDir, fileName), ensureJsonFile(filepath.Join(userDir, fileName), []models.LogsInfoDto{})
+	}
+
+	fileName = fmt.Sprintf(logFilePatternFile, utils.GetOnlyDate(date))
+
+	err = ensureJsonFile(filepath.Join(userDir, fileName), []models.LogsInfoDto{})
+
+	if err != nil {
+		return "", err
+	}
+
+	navigationDto.Date[utils.GetOnlyDate(date)] = fileName
 
-		err = os.WriteFile(logFileNavigationFile, content, 0644)
+	return filepath.Join(userDir, fileName), writeJsonFile(j.getNavigationFile(userId), navigationDto)
+}
+
+// migrateLegacyLayout moves the single-user user.json and the log files next
+// to it into the per-user layout.
+func (j *JsonStorageProvider) migrateLegacyLayout() error {
+	if _, err := os.Stat(legacyUserSettingsFile); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	var legacySettings *models.UserSettingsDto
+
+	err := readJsonFile(legacyUserSettingsFile, &legacySettings)
+
+	if err != nil {
+		return err
+	}
+
+	if legacySettings != nil && legacySettings.UserId != 0 {
+		logrus.Infof("Migrating legacy storage of user %d", legacySettings.UserId)
+
+		err = j.migrateLegacyLogs(legacySettings.UserId)
 
 		if err != nil {
-			return "", err
+			return err
 		}
-	}
 
-	return func() (string, error) {
-		if exist {
-			return fileName, nil
+		users := map[int64]*models.UserSettingsDto{}
+
+		if _, err = os.Stat(usersSettingsFile); err == nil {
+			users, err = j.readUsers()
+
+			if err != nil {
+				return err
+			}
 		}
-		file, err := os.Create(fileName)
-		err = file.Close()
 
-		if err != nil {
-			return "", err
+		if _, exist := users[legacySettings.UserId]; !exist {
+			users[legacySettings.UserId] = legacySettings
 		}
 
+		err = writeJsonFile(usersSettingsFile, users)
+
 		if err != nil {
-			return "", err
+			return err
 		}
+	}
 
-		emptyArray, err := json.Marshal([]models.LogsInfoDto{})
+	return removeJsonFile(legacyUserSettingsFile)
+}
 
-		if err != nil {
-			return "", err
+func (j *JsonStorageProvider) migrateLegacyLogs(userId int64) error {
+	if _, err := os.Stat(logFileNavigationFile); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	var legacyNavigation models.LogsNavigationDto
+
+	err := readJsonFile(logFileNavigationFile, &legacyNavigation)
+
+	if err != nil {
+		return err
+	}
+
+	userDir := j.getUserLogsDir(userId)
+
+	err = os.MkdirAll(userDir, 0755)
+
+	if err != nil {
+		return err
+	}
+
+	for _, fileName := range legacyNavigation.Date {
+		for _, name := range []string{fileName, fileName + backupFileSuffix} {
+			err = os.Rename(name, filepath.Join(userDir, name))
+
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
 		}
-		err = os.WriteFile(fileName, emptyArray, 0644)
+	}
 
-		if err != nil {
-			return "", err
+	err = writeJsonFile(j.getNavigationFile(userId), legacyNavigation)
+
+	if err != nil {
+		return err
+	}
+
+	return removeJsonFile(logFileNavigationFile)
+}
+
+func containsLog(logs []models.LogsInfoDto, logId string) bool {
+	for _, v := range logs {
+		if v.Id == logId {
+			return true
 		}
+	}
 
-		return fileName, nil
-	}()
+	return false
 }
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"errors"
+	"logs-aggregator-bot/models"
+	"time"
+)
+
+const (
+	StorageTypeJson   = "json"
+	StorageTypeSqlite = "sqlite"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrInviteNotFound = errors.New("invite not found")
+)
+
+type UserSettingsStorage interface {
+	GetUsers() ([]models.UserSettingsDto, error)
+	GetUserSettings(userId int64) (*models.UserSettingsDto, error)
+	SetUserSettings(dto *models.UserSettingsDto) error
+	UpdateUserSettings(userId int64, update func(settings *models.UserSettingsDto) error) (*models.UserSettingsDto, error)
+}
+
+type LogsStorage interface {
+	InsertNewLogRecord(userId int64, date time.Time, log *models.LogsInfoDto) error
+	UpdateLogRecord(userId int64, log *models.LogsInfoDto) error
+	GetLogRecords(userId int64, date time.Time) ([]models.LogsInfoDto, error)
+	GetLastLogByIssueKey(userId int64, issueKey string) (*models.LogsInfoDto, error)
+	UpdateLogSyncStatus(userId int64, log *models.LogsInfoDto) error
+	DeleteLogRecord(userId int64, date string, logId string) error
+}
+
+type LogsNavigationStorage interface {
+	GetDatesWithLogs(userId int64) ([]string, error)
+	DeleteLogsByDate(userId int64, date string) error
+}
+
+type TrashStorage interface {
+	TrashLogsByDate(userId int64, date string, trashedAt time.Time) error
+	GetTrashedDays(userId int64) ([]models.TrashedDayDto, error)
+	RestoreLogsByDate(userId int64, date string) ([]models.LogsInfoDto, error)
+	PurgeTrash(trashedBefore time.Time) error
+}
+
+type InviteStorage interface {
+	CreateInvite(invite *models.InviteDto) error
+	UseInvite(code string, userId int64, usedAt time.Time) (*models.InviteDto, error)
+}
+
+type WebhookStorage interface {
+	EnqueueWebhookDeliveries(deliveries []*models.WebhookDeliveryDto) error
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDeliveryDto, error)
+	UpdateWebhookDelivery(delivery *models.WebhookDeliveryDto) error
+	DeleteWebhookDelivery(id string) error
+}
+
+type StorageProvider interface {
+	UserSettingsStorage
+	LogsStorage
+	LogsNavigationStorage
+	TrashStorage
+	InviteStorage
+	WebhookStorage
+}
+
+var (
+	_ StorageProvider = (*JsonStorageProvider)(nil)
+	_ StorageProvider = (*SqliteStorageProvider)(nil)
+)
@@ -0,0 +1,708 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DefaultSqliteFile = "logs.db"
+	// sqliteTimeLayout keeps the times in UTC with a fixed width, so that the
+	// text columns sort in time order.
+	sqliteTimeLayout = "2006-01-02T15:04:05.000Z"
+)
+
+var sqliteMigrations = []string{
+	`CREATE TABLE user_settings (
+		id   INTEGER PRIMARY KEY CHECK (id = 1),
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE logs (
+		id              TEXT PRIMARY KEY,
+		log_date        TEXT NOT NULL,
+		start_work_time TEXT NOT NULL,
+		end_work_time   TEXT NOT NULL,
+		message         TEXT NOT NULL
+	)`,
+	`CREATE INDEX idx_logs_log_date ON logs (log_date)`,
+	`CREATE TABLE users (
+		user_id INTEGER PRIMARY KEY,
+		data    TEXT NOT NULL
+	);
+	INSERT INTO users (user_id, data)
+		SELECT json_extract(data, '$.UserId'), data FROM user_settings WHERE json_extract(data, '$.UserId') != 0;
+	ALTER TABLE logs ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+	UPDATE logs SET user_id = COALESCE((SELECT json_extract(data, '$.UserId') FROM user_settings), 0);
+	DROP TABLE user_settings;
+	DROP INDEX idx_logs_log_date;
+	CREATE INDEX idx_logs_user_date ON logs (user_id, log_date)`,
+	`CREATE TABLE invites (
+		code       TEXT PRIMARY KEY,
+		created_by INTEGER NOT NULL,
+		created_at TEXT NOT NULL,
+		expires_at TEXT NOT NULL,
+		used_by    INTEGER NOT NULL DEFAULT 0,
+		used_at    TEXT NOT NULL DEFAULT ''
+	)`,
+	`ALTER TABLE logs ADD COLUMN project TEXT NOT NULL DEFAULT '';
+	ALTER TABLE logs ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+	CREATE INDEX idx_logs_user_project ON logs (user_id, project)`,
+	`ALTER TABLE logs ADD COLUMN issue_key TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_logs_user_issue_key ON logs (user_id, issue_key, end_work_time)`,
+	`ALTER TABLE logs ADD COLUMN jira_worklog_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE logs ADD COLUMN sync_status TEXT NOT NULL DEFAULT '';
+	ALTER TABLE logs ADD COLUMN sync_error TEXT NOT NULL DEFAULT ''`,
+	`CREATE TABLE webhook_deliveries (
+		id              TEXT PRIMARY KEY,
+		event_type      TEXT NOT NULL,
+		url             TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at INTEGER NOT NULL,
+		last_error      TEXT NOT NULL DEFAULT '',
+		created_at      TEXT NOT NULL
+	);
+	CREATE INDEX idx_webhook_deliveries_next_attempt ON webhook_deliveries (next_attempt_at)`,
+	`CREATE TABLE trashed_logs (
+		id              TEXT PRIMARY KEY,
+		user_id         INTEGER NOT NULL,
+		log_date        TEXT NOT NULL,
+		start_work_time TEXT NOT NULL,
+		end_work_time   TEXT NOT NULL,
+		message         TEXT NOT NULL,
+		project         TEXT NOT NULL DEFAULT '',
+		tags            TEXT NOT NULL DEFAULT '[]',
+		issue_key       TEXT NOT NULL DEFAULT '',
+		jira_worklog_id TEXT NOT NULL DEFAULT '',
+		sync_status     TEXT NOT NULL DEFAULT '',
+		sync_error      TEXT NOT NULL DEFAULT '',
+		trashed_at      INTEGER NOT NULL
+	);
+	CREATE INDEX idx_trashed_logs_user_date ON trashed_logs (user_id, log_date);
+	CREATE INDEX idx_trashed_logs_trashed_at ON trashed_logs (trashed_at)`,
+	`ALTER TABLE logs ADD COLUMN jira_issue_key TEXT NOT NULL DEFAULT '';
+	ALTER TABLE trashed_logs ADD COLUMN jira_issue_key TEXT NOT NULL DEFAULT '';
+	UPDATE logs SET jira_issue_key = issue_key WHERE jira_worklog_id != '';
+	UPDATE trashed_logs SET jira_issue_key = issue_key WHERE jira_worklog_id != ''`,
+	`UPDATE logs SET
+		start_work_time = strftime('%Y-%m-%dT%H:%M:%fZ', start_work_time),
+		end_work_time = strftime('%Y-%m-%dT%H:%M:%fZ', end_work_time);
+	UPDATE trashed_logs SET
+		start_work_time = strftime('%Y-%m-%dT%H:%M:%fZ', start_work_time),
+		end_work_time = strftime('%Y-%m-%dT%H:%M:%fZ', end_work_time)`,
+}
+
+type SqliteStorageProvider struct {
+	db *sql.DB
+}
+
+func NewSqliteStorageProvider(path string) (*SqliteStorageProvider, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	provider := &SqliteStorageProvider{db: db}
+
+	err = provider.migrate()
+
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func (s *SqliteStorageProvider) Close() error {
+	return s.db.Close()
+}
+
+func (s *SqliteStorageProvider) GetUsers() ([]models.UserSettingsDto, error) {
+	rows, err := s.db.Query(`SELECT data FROM users ORDER BY user_id`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make([]models.UserSettingsDto, 0)
+
+	for rows.Next() {
+		var (
+			data     string
+			settings models.UserSettingsDto
+		)
+
+		err = rows.Scan(&data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(data), &settings)
+
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, settings)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *SqliteStorageProvider) GetUserSettings(userId int64) (*models.UserSettingsDto, error) {
+	return getSqliteUserSettings(s.db, userId)
+}
+
+func (s *SqliteStorageProvider) SetUserSettings(dto *models.UserSettingsDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return setSqliteUserSettings(tx, dto)
+	})
+}
+
+func (s *SqliteStorageProvider) UpdateUserSettings(userId int64, update func(settings *models.UserSettingsDto) error) (*models.UserSettingsDto, error) {
+	var settings *models.UserSettingsDto
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+
+		settings, err = getSqliteUserSettings(tx, userId)
+
+		if err != nil {
+			return err
+		}
+
+		err = update(settings)
+
+		if err != nil {
+			return err
+		}
+
+		return setSqliteUserSettings(tx, settings)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (s *SqliteStorageProvider) InsertNewLogRecord(userId int64, date time.Time, log *models.LogsInfoDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		tags, err := json.Marshal(log.Tags)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO logs (id, user_id, log_date, start_work_time, end_work_time, message, project, tags, issue_key, jira_worklog_id, jira_issue_key, sync_status, sync_error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			log.Id,
+			userId,
+			utils.GetOnlyDate(date),
+			formatSqliteTime(log.StartWorkTime),
+			formatSqliteTime(log.EndWorkTime),
+			log.Message,
+			log.Project,
+			string(tags),
+			log.IssueKey,
+			log.JiraWorklogId,
+			log.JiraIssueKey,
+			log.SyncStatus,
+			log.SyncError,
+		)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) UpdateLogRecord(userId int64, log *models.LogsInfoDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		tags, err := json.Marshal(log.Tags)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE logs SET start_work_time = ?, end_work_time = ?, message = ?, project = ?, tags = ?, issue_key = ? WHERE id = ? AND user_id = ?`,
+			formatSqliteTime(log.StartWorkTime),
+			formatSqliteTime(log.EndWorkTime),
+			log.Message,
+			log.Project,
+			string(tags),
+			log.IssueKey,
+			log.Id,
+			userId,
+		)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) UpdateLogSyncStatus(userId int64, log *models.LogsInfoDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE logs SET jira_worklog_id = ?, jira_issue_key = ?, sync_status = ?, sync_error = ? WHERE id = ? AND user_id = ?`,
+			log.JiraWorklogId, log.JiraIssueKey, log.SyncStatus, log.SyncError, log.Id, userId)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) GetLogRecords(userId int64, date time.Time) ([]models.LogsInfoDto, error) {
+	rows, err := s.db.Query(`SELECT `+sqliteLogColumns+` FROM logs
+		WHERE user_id = ? AND log_date = ? ORDER BY start_work_time`, userId, utils.GetOnlyDate(date))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSqliteLogs(rows)
+}
+
+func (s *SqliteStorageProvider) GetLastLogByIssueKey(userId int64, issueKey string) (*models.LogsInfoDto, error) {
+	rows, err := s.db.Query(`SELECT `+sqliteLogColumns+` FROM logs
+		WHERE user_id = ? AND issue_key = ? ORDER BY end_work_time DESC LIMIT 1`, userId, issueKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	logData, err := scanSqliteLogs(rows)
+
+	if err != nil || len(logData) == 0 {
+		return nil, err
+	}
+
+	return &logData[0], nil
+}
+
+func (s *SqliteStorageProvider) GetDatesWithLogs(userId int64) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT log_date FROM logs WHERE user_id = ? ORDER BY log_date`, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	dates := make([]string, 0)
+
+	for rows.Next() {
+		var date string
+
+		err = rows.Scan(&date)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dates = append(dates, date)
+	}
+
+	return dates, rows.Err()
+}
+
+func (s *SqliteStorageProvider) DeleteLogsByDate(userId int64, date string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM logs WHERE user_id = ? AND log_date = ?`, userId, date)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) DeleteLogRecord(userId int64, date string, logId string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM logs WHERE user_id = ? AND log_date = ? AND id = ?`, userId, date, logId)
+		return err
+	})
+}
+
+// TrashLogsByDate moves the logs of the date into trashed_logs. A day trashed
+// again before the purge is merged into the logs already in the trash.
+func (s *SqliteStorageProvider) TrashLogsByDate(userId int64, date string, trashedAt time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO trashed_logs (user_id, log_date, `+sqliteLogColumns+`, trashed_at)
+			SELECT user_id, log_date, `+sqliteLogColumns+`, ? FROM logs WHERE user_id = ? AND log_date = ?`,
+			trashedAt.UnixMilli(), userId, date)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE trashed_logs SET trashed_at = ? WHERE user_id = ? AND log_date = ?`, trashedAt.UnixMilli(), userId, date)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM logs WHERE user_id = ? AND log_date = ?`, userId, date)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) GetTrashedDays(userId int64) ([]models.TrashedDayDto, error) {
+	rows, err := s.db.Query(`SELECT log_date, COUNT(*), MAX(trashed_at) FROM trashed_logs
+		WHERE user_id = ? GROUP BY log_date ORDER BY log_date`, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	days := make([]models.TrashedDayDto, 0)
+
+	for rows.Next() {
+		var (
+			day       models.TrashedDayDto
+			trashedAt int64
+		)
+
+		err = rows.Scan(&day.Date, &day.LogsCount, &trashedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		day.TrashedAt = time.UnixMilli(trashedAt)
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// RestoreLogsByDate brings the day back from the trash, merging it with the
+// logs recorded for the date since. Nothing is restored for an unknown date.
+func (s *SqliteStorageProvider) RestoreLogsByDate(userId int64, date string) ([]models.LogsInfoDto, error) {
+	var logData []models.LogsInfoDto
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT `+sqliteLogColumns+` FROM trashed_logs
+			WHERE user_id = ? AND log_date = ? ORDER BY start_work_time`, userId, date)
+
+		if err != nil {
+			return err
+		}
+
+		logData, err = scanSqliteLogs(rows)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT OR IGNORE INTO logs (user_id, log_date, `+sqliteLogColumns+`)
+			SELECT user_id, log_date, `+sqliteLogColumns+` FROM trashed_logs WHERE user_id = ? AND log_date = ?`, userId, date)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM trashed_logs WHERE user_id = ? AND log_date = ?`, userId, date)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(logData) == 0 {
+		return nil, nil
+	}
+
+	return logData, nil
+}
+
+func (s *SqliteStorageProvider) PurgeTrash(trashedBefore time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM trashed_logs WHERE trashed_at < ?`, trashedBefore.UnixMilli())
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) CreateInvite(invite *models.InviteDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO invites (code, created_by, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+			invite.Code,
+			invite.CreatedBy,
+			formatSqliteTime(invite.CreatedAt),
+			formatSqliteTime(invite.ExpiresAt),
+		)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) UseInvite(code string, userId int64, usedAt time.Time) (*models.InviteDto, error) {
+	invite := &models.InviteDto{Code: code, UsedBy: userId, UsedAt: usedAt}
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		var createdAt, expiresAt string
+
+		err := tx.QueryRow(`SELECT created_by, created_at, expires_at FROM invites WHERE code = ? AND used_by = 0`, code).
+			Scan(&invite.CreatedBy, &createdAt, &expiresAt)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInviteNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		invite.CreatedAt, err = parseSqliteTime(createdAt)
+
+		if err != nil {
+			return err
+		}
+
+		invite.ExpiresAt, err = parseSqliteTime(expiresAt)
+
+		if err != nil {
+			return err
+		}
+
+		if usedAt.After(invite.ExpiresAt) {
+			return ErrInviteNotFound
+		}
+
+		_, err = tx.Exec(`UPDATE invites SET used_by = ?, used_at = ? WHERE code = ?`, userId, formatSqliteTime(usedAt), code)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (s *SqliteStorageProvider) EnqueueWebhookDeliveries(deliveries []*models.WebhookDeliveryDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, v := range deliveries {
+			_, err := tx.Exec(`INSERT INTO webhook_deliveries (id, event_type, url, payload, attempts, next_attempt_at, last_error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				v.Id,
+				v.EventType,
+				v.Url,
+				v.Payload,
+				v.Attempts,
+				v.NextAttemptAt.UnixMilli(),
+				v.LastError,
+				formatSqliteTime(v.CreatedAt),
+			)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetDueWebhookDeliveries keeps the attempt time as unix milliseconds, so the
+// index orders it regardless of the time zone it was written in.
+func (s *SqliteStorageProvider) GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDeliveryDto, error) {
+	rows, err := s.db.Query(`SELECT id, event_type, url, payload, attempts, next_attempt_at, last_error, created_at FROM webhook_deliveries WHERE next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?`,
+		now.UnixMilli(), limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var result []models.WebhookDeliveryDto
+
+	for rows.Next() {
+		var delivery models.WebhookDeliveryDto
+		var nextAttemptAt int64
+		var createdAt string
+
+		err = rows.Scan(&delivery.Id, &delivery.EventType, &delivery.Url, &delivery.Payload, &delivery.Attempts, &nextAttemptAt, &delivery.LastError, &createdAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.NextAttemptAt = time.UnixMilli(nextAttemptAt)
+		delivery.CreatedAt, err = parseSqliteTime(createdAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, delivery)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SqliteStorageProvider) UpdateWebhookDelivery(delivery *models.WebhookDeliveryDto) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE webhook_deliveries SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+			delivery.Attempts, delivery.NextAttemptAt.UnixMilli(), delivery.LastError, delivery.Id)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) DeleteWebhookDelivery(id string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE id = ?`, id)
+		return err
+	})
+}
+
+func (s *SqliteStorageProvider) migrate() error {
+	var version int
+
+	err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version)
+
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		err = s.withTx(func(tx *sql.Tx) error {
+			_, err := tx.Exec(sqliteMigrations[i])
+
+			if err != nil {
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+
+			_, err = tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1))
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SqliteStorageProvider) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const sqliteLogColumns = `id, start_work_time, end_work_time, message, project, tags, issue_key, jira_worklog_id, jira_issue_key, sync_status, sync_error`
+
+func scanSqliteLogs(rows *sql.Rows) ([]models.LogsInfoDto, error) {
+	defer rows.Close()
+
+	logData := make([]models.LogsInfoDto, 0)
+
+	for rows.Next() {
+		var (
+			log       models.LogsInfoDto
+			startTime string
+			endTime   string
+			tags      string
+		)
+
+		err := rows.Scan(&log.Id, &startTime, &endTime, &log.Message, &log.Project, &tags, &log.IssueKey, &log.JiraWorklogId, &log.JiraIssueKey, &log.SyncStatus, &log.SyncError)
+
+		if err != nil {
+			return nil, err
+		}
+
+		log.StartWorkTime, err = parseSqliteTime(startTime)
+
+		if err != nil {
+			return nil, err
+		}
+
+		log.EndWorkTime, err = parseSqliteTime(endTime)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(tags), &log.Tags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		logData = append(logData, log)
+	}
+
+	return logData, rows.Err()
+}
+
+type sqliteQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func getSqliteUserSettings(q sqliteQuerier, userId int64) (*models.UserSettingsDto, error) {
+	var data string
+
+	err := q.QueryRow(`SELECT data FROM users WHERE user_id = ?`, userId).Scan(&data)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var settings *models.UserSettingsDto
+
+	err = json.Unmarshal([]byte(data), &settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func setSqliteUserSettings(q sqliteQuerier, dto *models.UserSettingsDto) error {
+	data, err := json.Marshal(dto)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`INSERT INTO users (user_id, data) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET data = excluded.data`, dto.UserId, string(data))
+	return err
+}
+
+func formatSqliteTime(date time.Time) string {
+	return date.UTC().Format(sqliteTimeLayout)
+}
+
+func parseSqliteTime(value string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339Nano, value)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return date.Local(), nil
+}
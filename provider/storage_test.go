@@ -0,0 +1,481 @@
+package provider
+
+import (
+	"errors"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const storageTestUserId = 1
+
+// forEachProvider runs the test against every backend, each one in a fresh
+// directory since the json files are kept relative to the working one.
+func forEachProvider(t *testing.T, test func(t *testing.T, storage StorageProvider)) {
+	providers := map[string]func(t *testing.T) StorageProvider{
+		StorageTypeJson: func(t *testing.T) StorageProvider {
+			storage, err := NewJsonStorageProvider()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return storage
+		},
+		StorageTypeSqlite: func(t *testing.T) StorageProvider {
+			storage, err := NewSqliteStorageProvider(filepath.Join(t.TempDir(), DefaultSqliteFile))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() {
+				_ = storage.Close()
+			})
+
+			return storage
+		},
+	}
+
+	for name, newStorage := range providers {
+		t.Run(name, func(t *testing.T) {
+			chdirTemp(t)
+
+			storage := newStorage(t)
+
+			err := storage.SetUserSettings(&models.UserSettingsDto{UserId: storageTestUserId})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test(t, storage)
+		})
+	}
+}
+
+func chdirTemp(t *testing.T) {
+	wd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(t.TempDir())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
+func newStorageTestLog(id string, start time.Time, length time.Duration, issueKey string) *models.LogsInfoDto {
+	return &models.LogsInfoDto{
+		Id:            id,
+		StartWorkTime: start,
+		EndWorkTime:   start.Add(length),
+		Message:       id + " message",
+		Project:       "bot",
+		Tags:          []string{"review"},
+		IssueKey:      issueKey,
+	}
+}
+
+func insertStorageTestLogs(t *testing.T, storage StorageProvider, logs ...*models.LogsInfoDto) {
+	for _, v := range logs {
+		err := storage.InsertNewLogRecord(storageTestUserId, v.EndWorkTime, v)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func getStorageTestLogs(t *testing.T, storage StorageProvider, date time.Time) map[string]models.LogsInfoDto {
+	logs, err := storage.GetLogRecords(storageTestUserId, date)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := map[string]models.LogsInfoDto{}
+
+	for _, v := range logs {
+		result[v.Id] = v
+	}
+
+	return result
+}
+
+func getStorageTestDates(t *testing.T, storage StorageProvider) []string {
+	dates, err := storage.GetDatesWithLogs(storageTestUserId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dates
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestStorageUserSettings(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		_, err := storage.GetUserSettings(2)
+
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("got error %v, want %v", err, ErrUserNotFound)
+		}
+
+		_, err = storage.UpdateUserSettings(storageTestUserId, func(settings *models.UserSettingsDto) error {
+			settings.Status = constants.UserStatusActive
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		failure := errors.New("failure")
+
+		_, err = storage.UpdateUserSettings(storageTestUserId, func(settings *models.UserSettingsDto) error {
+			settings.Status = constants.UserStatusBlocked
+			return failure
+		})
+
+		if !errors.Is(err, failure) {
+			t.Errorf("got error %v, want %v", err, failure)
+		}
+
+		users, err := storage.GetUsers()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 1 || users[0].UserId != storageTestUserId || users[0].Status != constants.UserStatusActive {
+			t.Errorf("got users %+v", users)
+		}
+	})
+}
+
+func TestStorageLogs(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		day := time.Date(2024, 5, 13, 0, 0, 0, 0, time.Local)
+		first := newStorageTestLog("first", day.Add(9*time.Hour), time.Hour, "PROJ-1")
+		second := newStorageTestLog("second", day.Add(11*time.Hour), 30*time.Minute, "")
+		insertStorageTestLogs(t, storage, second, first)
+
+		logs := getStorageTestLogs(t, storage, day)
+
+		if len(logs) != 2 || !logs["first"].EndWorkTime.Equal(first.EndWorkTime) || logs["first"].Tags[0] != "review" {
+			t.Fatalf("got logs %+v", logs)
+		}
+
+		first.EndWorkTime = first.EndWorkTime.Add(15 * time.Minute)
+		first.Message = "edited"
+		first.IssueKey = "PROJ-2"
+
+		err := storage.UpdateLogRecord(storageTestUserId, first)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first.JiraWorklogId = "7"
+		first.JiraIssueKey = "PROJ-2"
+		first.SyncStatus = constants.SyncStatusSynced
+
+		err = storage.UpdateLogSyncStatus(storageTestUserId, first)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stored := getStorageTestLogs(t, storage, day)["first"]
+
+		if !stored.EndWorkTime.Equal(first.EndWorkTime) || stored.Message != "edited" || stored.IssueKey != "PROJ-2" ||
+			stored.JiraWorklogId != "7" || stored.JiraIssueKey != "PROJ-2" || stored.SyncStatus != constants.SyncStatusSynced {
+			t.Errorf("got log %+v", stored)
+		}
+
+		err = storage.DeleteLogRecord(storageTestUserId, "2024-05-13", "second")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if dates := getStorageTestDates(t, storage); !equalStrings(dates, []string{"2024-05-13"}) {
+			t.Errorf("got dates %v after deleting one of two logs", dates)
+		}
+
+		err = storage.DeleteLogRecord(storageTestUserId, "2024-05-13", "first")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if dates := getStorageTestDates(t, storage); len(dates) != 0 {
+			t.Errorf("got dates %v after deleting the last log", dates)
+		}
+	})
+}
+
+func TestStorageLookupKeepsEmptyDays(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		logs, err := storage.GetLogRecords(storageTestUserId, time.Date(2024, 5, 14, 12, 0, 0, 0, time.Local))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(logs) != 0 {
+			t.Errorf("got logs %+v", logs)
+		}
+
+		if dates := getStorageTestDates(t, storage); len(dates) != 0 {
+			t.Errorf("got dates %v after a lookup", dates)
+		}
+	})
+}
+
+func TestStorageLastLogByIssueKey(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		// The earlier log ends at 08:00 UTC but its text with the +03:00 offset
+		// sorts after the later one.
+		earlier := newStorageTestLog("earlier", time.Date(2024, 5, 13, 10, 0, 0, 0, time.FixedZone("", 3*60*60)), time.Hour, "PROJ-1")
+		later := newStorageTestLog("later", time.Date(2024, 5, 13, 8, 30, 0, 0, time.UTC), 30*time.Minute, "PROJ-1")
+		older := newStorageTestLog("older", time.Date(2024, 5, 10, 9, 0, 0, 0, time.Local), time.Hour, "PROJ-1")
+		other := newStorageTestLog("other", time.Date(2024, 5, 14, 9, 0, 0, 0, time.Local), time.Hour, "PROJ-2")
+
+		for _, v := range []*models.LogsInfoDto{earlier, later} {
+			err := storage.InsertNewLogRecord(storageTestUserId, time.Date(2024, 5, 13, 12, 0, 0, 0, time.Local), v)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		insertStorageTestLogs(t, storage, older, other)
+
+		last, err := storage.GetLastLogByIssueKey(storageTestUserId, "PROJ-1")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if last == nil || last.Id != "later" {
+			t.Errorf("got last log %+v, want later", last)
+		}
+
+		last, err = storage.GetLastLogByIssueKey(storageTestUserId, "PROJ-3")
+
+		if err != nil || last != nil {
+			t.Errorf("got last log %+v error %v, want none", last, err)
+		}
+	})
+}
+
+func TestStorageTrash(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.Local)
+		kept := newStorageTestLog("kept", time.Date(2024, 5, 13, 9, 0, 0, 0, time.Local), time.Hour, "")
+		trashed := newStorageTestLog("trashed", time.Date(2024, 5, 14, 9, 0, 0, 0, time.Local), time.Hour, "PROJ-1")
+		purged := newStorageTestLog("purged", time.Date(2024, 5, 15, 9, 0, 0, 0, time.Local), time.Hour, "")
+		insertStorageTestLogs(t, storage, kept, trashed, purged)
+
+		err := storage.TrashLogsByDate(storageTestUserId, "2024-05-14", now.Add(-time.Hour))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = storage.TrashLogsByDate(storageTestUserId, "2024-05-15", now.AddDate(0, 0, -31))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if dates := getStorageTestDates(t, storage); !equalStrings(dates, []string{"2024-05-13"}) {
+			t.Errorf("got dates %v after trashing", dates)
+		}
+
+		days, err := storage.GetTrashedDays(storageTestUserId)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(days) != 2 || days[0].Date != "2024-05-14" || days[0].LogsCount != 1 || !days[0].TrashedAt.Equal(now.Add(-time.Hour)) {
+			t.Errorf("got trashed days %+v", days)
+		}
+
+		err = storage.PurgeTrash(now.AddDate(0, 0, -30))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		days, err = storage.GetTrashedDays(storageTestUserId)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(days) != 1 || days[0].Date != "2024-05-14" {
+			t.Errorf("got trashed days %+v after the purge", days)
+		}
+
+		restored, err := storage.RestoreLogsByDate(storageTestUserId, "2024-05-14")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(restored) != 1 || restored[0].Id != "trashed" {
+			t.Errorf("got restored logs %+v", restored)
+		}
+
+		if dates := getStorageTestDates(t, storage); !equalStrings(dates, []string{"2024-05-13", "2024-05-14"}) {
+			t.Errorf("got dates %v after the restore", dates)
+		}
+
+		restored, err = storage.RestoreLogsByDate(storageTestUserId, "2024-05-15")
+
+		if err != nil || restored != nil {
+			t.Errorf("got restored logs %+v error %v for a purged day", restored, err)
+		}
+
+		last, err := storage.GetLastLogByIssueKey(storageTestUserId, "PROJ-1")
+
+		if err != nil || last == nil || last.Id != "trashed" {
+			t.Errorf("got last log %+v error %v after the restore", last, err)
+		}
+	})
+}
+
+func TestStorageInvites(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		now := time.Date(2024, 5, 13, 12, 0, 0, 0, time.Local)
+
+		for _, code := range []string{"valid", "expired"} {
+			err := storage.CreateInvite(&models.InviteDto{Code: code, CreatedBy: storageTestUserId, CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		invite, err := storage.UseInvite("valid", 2, now)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if invite.CreatedBy != storageTestUserId || invite.UsedBy != 2 || !invite.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("got invite %+v", invite)
+		}
+
+		tests := []struct {
+			code   string
+			usedAt time.Time
+		}{
+			{code: "valid", usedAt: now},
+			{code: "expired", usedAt: now.Add(2 * time.Hour)},
+			{code: "unknown", usedAt: now},
+		}
+
+		for _, tt := range tests {
+			_, err = storage.UseInvite(tt.code, 3, tt.usedAt)
+
+			if !errors.Is(err, ErrInviteNotFound) {
+				t.Errorf("got error %v for %s, want %v", err, tt.code, ErrInviteNotFound)
+			}
+		}
+	})
+}
+
+func TestStorageWebhookDeliveries(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, storage StorageProvider) {
+		now := time.Date(2024, 5, 13, 12, 0, 0, 0, time.Local)
+		deliveries := []*models.WebhookDeliveryDto{
+			{Id: "late", Url: "https://example.com", Payload: "{}", NextAttemptAt: now.Add(-time.Minute), CreatedAt: now},
+			{Id: "early", Url: "https://example.com", Payload: "{}", NextAttemptAt: now.Add(-time.Hour), CreatedAt: now},
+			{Id: "future", Url: "https://example.com", Payload: "{}", NextAttemptAt: now.Add(time.Hour), CreatedAt: now},
+		}
+
+		err := storage.EnqueueWebhookDeliveries(deliveries)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		getDueIds := func(limit int) []string {
+			due, err := storage.GetDueWebhookDeliveries(now, limit)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+
+			for _, v := range due {
+				ids = append(ids, v.Id)
+			}
+
+			return ids
+		}
+
+		if ids := getDueIds(10); !equalStrings(ids, []string{"early", "late"}) {
+			t.Errorf("got due deliveries %v", ids)
+		}
+
+		if ids := getDueIds(1); !equalStrings(ids, []string{"early"}) {
+			t.Errorf("got due deliveries %v with limit", ids)
+		}
+
+		deliveries[1].Attempts = 1
+		deliveries[1].NextAttemptAt = now.Add(time.Minute)
+		deliveries[1].LastError = "timeout"
+
+		err = storage.UpdateWebhookDelivery(deliveries[1])
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = storage.DeleteWebhookDelivery("late")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ids := getDueIds(10); len(ids) != 0 {
+			t.Errorf("got due deliveries %v after update and delete", ids)
+		}
+
+		now = now.Add(2 * time.Minute)
+
+		if ids := getDueIds(10); !equalStrings(ids, []string{"early"}) {
+			t.Errorf("got due deliveries %v after the retry delay", ids)
+		}
+	})
+}
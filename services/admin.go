@@ -0,0 +1,288 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/provider"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	inviteTTL = 7 * 24 * time.Hour
+	// startCooldown is how often users without access are answered on /start.
+	startCooldown = time.Minute
+)
+
+func (a *ApiHandler) HandleStartCommand(userId int64, userName string, payload string) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil && !errors.Is(err, provider.ErrUserNotFound) {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	known := err == nil
+
+	// A stranger is registered and announced to the admins only once, repeated
+	// /start are ignored until the cooldown passes.
+	if !known && !a.allowStart(userId) {
+		return
+	}
+
+	// A pending user may still join with an invite received after a bare /start.
+	if known && (settings.Status != constants.UserStatusPending || payload == "") {
+		if settings.Status == constants.UserStatusActive || a.allowStart(userId) {
+			a.answerKnownUser(settings)
+		}
+
+		return
+	}
+
+	status := constants.UserStatusPending
+
+	if payload != "" {
+		_, err = a.provider.UseInvite(payload, userId, time.Now())
+
+		if err != nil && !errors.Is(err, provider.ErrInviteNotFound) {
+			logrus.Errorf("Failed to use invite: %v", err)
+			return
+		}
+
+		if err == nil {
+			status = constants.UserStatusActive
+		}
+	}
+
+	if known && status == constants.UserStatusPending {
+		if a.allowStart(userId) {
+			a.sendMessage(userId, "Приглашение недействительно или уже использовано")
+			a.answerKnownUser(settings)
+		}
+
+		return
+	}
+
+	if known {
+		_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+			settings.Status = status
+			return nil
+		})
+	} else {
+		err = a.provider.SetUserSettings(&models.UserSettingsDto{
+			UserId:       userId,
+			Role:         constants.UserRoleUser,
+			Status:       status,
+			UserName:     userName,
+			CurrentState: constants.UserStateNone,
+		})
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if status == constants.UserStatusActive {
+		a.sendMessage(userId, "Добро пожаловать! Начните рабочий день командой /start_work_day")
+		a.notifyAdmins(fmt.Sprintf("Пользователь %s присоединился по приглашению", formatUser(userId, userName)))
+		return
+	}
+
+	a.sendMessage(userId, "Заявка на доступ отправлена администратору, ожидайте подтверждения")
+	a.notifyAdmins(fmt.Sprintf("Пользователь %s запрашивает доступ. Подтвердить: /%s %d, заблокировать: /%s %d",
+		formatUser(userId, userName), constants.ApproveUserCommand, userId, constants.BlockUserCommand, userId))
+}
+
+func (a *ApiHandler) HandleInviteCommand(userId int64) {
+	code, err := generateInviteCode()
+
+	if err != nil {
+		logrus.Errorf("Failed to generate invite code: %v", err)
+		return
+	}
+
+	now := time.Now()
+
+	err = a.provider.CreateInvite(&models.InviteDto{
+		Code:      code,
+		CreatedBy: userId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(inviteTTL),
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to create invite: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Одноразовое приглашение действует до %s:\nhttps://t.me/%s?start=%s\nили команда: /%s %s",
+		now.Add(inviteTTL).Format(time.DateTime), a.botName, code, constants.StartCommand, code))
+}
+
+func (a *ApiHandler) HandleApproveUserCommand(userId int64, args string) {
+	targetId, ok := a.parseTargetUser(userId, args)
+
+	if !ok {
+		return
+	}
+
+	_, err := a.provider.UpdateUserSettings(targetId, func(settings *models.UserSettingsDto) error {
+		settings.Status = constants.UserStatusActive
+		return nil
+	})
+
+	if errors.Is(err, provider.ErrUserNotFound) {
+		a.sendMessage(userId, "Пользователь не найден")
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to update user settings: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Пользователь %d подтвержден", targetId))
+	a.sendMessage(targetId, "Доступ к боту открыт. Начните рабочий день командой /start_work_day")
+}
+
+func (a *ApiHandler) HandleBlockUserCommand(userId int64, args string) {
+	targetId, ok := a.parseTargetUser(userId, args)
+
+	if !ok {
+		return
+	}
+
+	_, err := a.provider.UpdateUserSettings(targetId, func(settings *models.UserSettingsDto) error {
+		if settings.IsAdmin() {
+			return errors.New("admin can not be blocked")
+		}
+
+		settings.Status = constants.UserStatusBlocked
+		settings.CurrentState = constants.UserStateNone
+		return nil
+	})
+
+	if errors.Is(err, provider.ErrUserNotFound) {
+		a.sendMessage(userId, "Пользователь не найден")
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to block user %d: %v", targetId, err)
+		a.sendMessage(userId, "Не удалось заблокировать пользователя")
+		return
+	}
+
+	a.scheduler.Stop(targetId)
+	a.sendMessage(userId, fmt.Sprintf("Пользователь %d заблокирован", targetId))
+}
+
+func (a *ApiHandler) HandleListUsersCommand(userId int64) {
+	users, err := a.provider.GetUsers()
+
+	if err != nil {
+		logrus.Errorf("Failed to get users: %v", err)
+		return
+	}
+
+	messageText := "Пользователи:\n"
+
+	for _, v := range users {
+		status := v.Status
+
+		if status == "" {
+			status = constants.UserStatusActive
+		}
+
+		messageText += fmt.Sprintf("%s, роль: %s, статус: %s\n", formatUser(v.UserId, v.UserName), v.Role, status)
+	}
+
+	a.sendMessage(userId, messageText)
+}
+
+func (a *ApiHandler) answerKnownUser(settings *models.UserSettingsDto) {
+	switch {
+	case settings.Status == constants.UserStatusBlocked:
+		logrus.Warnf("Blocked user %d tried to start the bot", settings.UserId)
+	case settings.Status == constants.UserStatusPending:
+		a.sendMessage(settings.UserId, "Ваша заявка еще ожидает подтверждения администратора")
+	default:
+		a.sendMessage(settings.UserId, "Бот уже доступен. Начните рабочий день командой /start_work_day")
+	}
+}
+
+// allowStart reports whether /start of the user is answered, at most once per
+// startCooldown.
+func (a *ApiHandler) allowStart(userId int64) bool {
+	a.startsMu.Lock()
+	defer a.startsMu.Unlock()
+
+	now := time.Now()
+
+	for id, startedAt := range a.starts {
+		if now.Sub(startedAt) >= startCooldown {
+			delete(a.starts, id)
+		}
+	}
+
+	if _, exist := a.starts[userId]; exist {
+		return false
+	}
+
+	a.starts[userId] = now
+	return true
+}
+
+func (a *ApiHandler) parseTargetUser(userId int64, args string) (int64, bool) {
+	targetId, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+
+	if err != nil {
+		a.sendMessage(userId, "Укажите id пользователя, например: /approve 123456")
+		return 0, false
+	}
+
+	return targetId, true
+}
+
+func (a *ApiHandler) notifyAdmins(body string) {
+	users, err := a.provider.GetUsers()
+
+	if err != nil {
+		logrus.Errorf("Failed to get users: %v", err)
+		return
+	}
+
+	for _, v := range users {
+		if v.IsAdmin() {
+			a.sendMessage(v.UserId, body)
+		}
+	}
+}
+
+func formatUser(userId int64, userName string) string {
+	if userName == "" {
+		return strconv.FormatInt(userId, 10)
+	}
+
+	return fmt.Sprintf("@%s (%d)", userName, userId)
+}
+
+func generateInviteCode() (string, error) {
+	buf := make([]byte, 12)
+
+	_, err := rand.Read(buf)
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"testing"
+)
+
+const adminTestId = 100
+
+func getAdminTestMessages(tgCli *fakeTgClient, chatId int64) int {
+	tgCli.mu.Lock()
+	defer tgCli.mu.Unlock()
+
+	count := 0
+
+	for _, v := range tgCli.messages {
+		if v.ChatId == chatId {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestStartCommandFromUsersWithoutAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []models.UserSettingsDto
+		messages int
+		notices  int
+	}{
+		{name: "stranger", messages: 1, notices: 1},
+		{
+			name:     "pending",
+			settings: []models.UserSettingsDto{{UserId: 1, Role: constants.UserRoleUser, Status: constants.UserStatusPending}},
+			messages: 1,
+		},
+		{
+			name:     "blocked",
+			settings: []models.UserSettingsDto{{UserId: 1, Role: constants.UserRoleUser, Status: constants.UserStatusBlocked}},
+		},
+		{
+			name:     "active",
+			settings: []models.UserSettingsDto{{UserId: 1, Role: constants.UserRoleUser, Status: constants.UserStatusActive}},
+			messages: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin := models.UserSettingsDto{UserId: adminTestId, Role: constants.UserRoleAdmin, Status: constants.UserStatusActive}
+			tgCli := &fakeTgClient{}
+			handler := NewApiHandler(newFakeStorage(append(tt.settings, admin)...), tgCli, nil, nil, nil, "")
+
+			for range 3 {
+				handler.HandleStartCommand(1, "stranger", "")
+			}
+
+			if messages := getAdminTestMessages(tgCli, 1); messages != tt.messages {
+				t.Errorf("got %d answers, want %d", messages, tt.messages)
+			}
+
+			if notices := getAdminTestMessages(tgCli, adminTestId); notices != tt.notices {
+				t.Errorf("got %d admin notices, want %d", notices, tt.notices)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrInvalidApiToken = errors.New("invalid api token")
+
+// HandleApiTokenCommand issues a new REST API token, the previous one stops
+// working. Only the token hash is stored.
+func (a *ApiHandler) HandleApiTokenCommand(userId int64, args string) {
+	token := ""
+
+	if strings.TrimSpace(args) != disableSettingArg {
+		buf := make([]byte, 32)
+
+		_, err := rand.Read(buf)
+
+		if err != nil {
+			logrus.Errorf("Failed to generate api token: %v", err)
+			return
+		}
+
+		token = hex.EncodeToString(buf)
+	}
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.ApiTokenHash = ""
+
+		if token != "" {
+			settings.ApiTokenHash = hashApiToken(token)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if token == "" {
+		a.sendMessage(userId, "Токен API отозван")
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Ваш токен API: %s\nПередавайте его в заголовке Authorization: Bearer <токен>. Отозвать: /%s %s",
+		token, constants.ApiTokenCommand, disableSettingArg))
+}
+
+// AuthenticateApiToken returns the active user owning the token.
+func (a *ApiHandler) AuthenticateApiToken(token string) (*models.UserSettingsDto, error) {
+	if token == "" {
+		return nil, ErrInvalidApiToken
+	}
+
+	users, err := a.provider.GetUsers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashApiToken(token)
+
+	for _, v := range users {
+		if v.ApiTokenHash == "" || subtle.ConstantTimeCompare([]byte(v.ApiTokenHash), []byte(hash)) != 1 {
+			continue
+		}
+
+		if !v.IsActive() {
+			return nil, ErrInvalidApiToken
+		}
+
+		return &v, nil
+	}
+
+	return nil, ErrInvalidApiToken
+}
+
+func hashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cronExpressionSeparator = ";"
+	cronSearchYears         = 5
+)
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// cronExpression is a classic five field expression: minute, hour, day of
+// month, month and day of week. Fields accept "*", lists, ranges and steps.
+type cronExpression struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	anyDom      bool
+	anyDow      bool
+}
+
+type cronSchedule struct {
+	workWindow
+	expressions []*cronExpression
+}
+
+func parseCronSchedule(value string) ([]*cronExpression, error) {
+	var expressions []*cronExpression
+
+	for _, part := range strings.Split(value, cronExpressionSeparator) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		expression, err := parseCronExpression(part)
+
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	if len(expressions) == 0 {
+		return nil, fmt.Errorf("empty cron schedule")
+	}
+
+	return expressions, nil
+}
+
+func parseCronExpression(value string) (*cronExpression, error) {
+	fields := strings.Fields(value)
+
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", value, len(cronFields))
+	}
+
+	parsed := make([]map[int]bool, len(cronFields))
+
+	for i, field := range cronFields {
+		values, err := parseCronField(fields[i], field)
+
+		if err != nil {
+			return nil, err
+		}
+
+		parsed[i] = values
+	}
+
+	if parsed[4][7] {
+		parsed[4][0] = true
+	}
+
+	return &cronExpression{
+		minutes:     parsed[0],
+		hours:       parsed[1],
+		daysOfMonth: parsed[2],
+		months:      parsed[3],
+		daysOfWeek:  parsed[4],
+		anyDom:      fields[2] == "*",
+		anyDow:      fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(value string, field cronField) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+
+		if hasStep {
+			parsedStep, err := strconv.Atoi(stepPart)
+
+			if err != nil || parsedStep <= 0 {
+				return nil, fmt.Errorf("invalid step %q in %s field", stepPart, field.name)
+			}
+
+			step = parsedStep
+		}
+
+		from, to := field.min, field.max
+
+		if rangePart != "*" {
+			fromPart, toPart, isRange := strings.Cut(rangePart, "-")
+
+			parsedFrom, err := strconv.Atoi(fromPart)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s field", rangePart, field.name)
+			}
+
+			from, to = parsedFrom, parsedFrom
+
+			if isRange {
+				to, err = strconv.Atoi(toPart)
+
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q in %s field", rangePart, field.name)
+				}
+			} else if hasStep {
+				to = field.max
+			}
+		}
+
+		if from < field.min || to > field.max || from > to {
+			return nil, fmt.Errorf("value %q is out of range %d-%d in %s field", part, field.min, field.max, field.name)
+		}
+
+		for i := from; i <= to; i += step {
+			values[i] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (c *cronExpression) matchesDay(date time.Time) bool {
+	domMatch := c.daysOfMonth[date.Day()]
+	dowMatch := c.daysOfWeek[int(date.Weekday())]
+
+	if c.anyDom || c.anyDow {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+func (c *cronExpression) matchesHour(date time.Time) bool {
+	return c.months[int(date.Month())] && c.matchesDay(date) && c.hours[date.Hour()]
+}
+
+// Next walks the minutes after the given time in a single pass, jumping over
+// the hours no expression matches and the closed parts of the work window.
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	date := after.Truncate(time.Minute).Add(time.Minute)
+	limit := date.AddDate(cronSearchYears, 0, 0)
+
+	for date.Before(limit) {
+		if !s.IsOpen(date) {
+			open := s.NextOpen(date)
+
+			if !open.After(date) {
+				return time.Time{}
+			}
+
+			date = open
+			continue
+		}
+
+		hourMatched := false
+
+		for _, v := range s.expressions {
+			if !v.matchesHour(date) {
+				continue
+			}
+
+			if v.minutes[date.Minute()] {
+				return date
+			}
+
+			hourMatched = true
+		}
+
+		if hourMatched {
+			date = date.Add(time.Minute)
+		} else {
+			date = time.Date(date.Year(), date.Month(), date.Day(), date.Hour()+1, 0, 0, 0, date.Location())
+		}
+	}
+
+	return time.Time{}
+}
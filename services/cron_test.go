@@ -0,0 +1,157 @@
+package services
+
+import (
+	"logs-aggregator-bot/models"
+	"testing"
+	"time"
+)
+
+func TestCronExpressionNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		after      time.Time
+		want       time.Time
+	}{
+		{
+			name:       "next step within the hour",
+			expression: "*/15 9-18 * * 1-5",
+			after:      time.Date(2024, 5, 13, 9, 7, 30, 0, time.Local),
+			want:       time.Date(2024, 5, 13, 9, 15, 0, 0, time.Local),
+		},
+		{
+			name:       "strictly after",
+			expression: "0 9 * * *",
+			after:      time.Date(2024, 5, 13, 9, 0, 0, 0, time.Local),
+			want:       time.Date(2024, 5, 14, 9, 0, 0, 0, time.Local),
+		},
+		{
+			name:       "next working day",
+			expression: "*/15 9-18 * * 1-5",
+			after:      time.Date(2024, 5, 17, 18, 45, 0, 0, time.Local),
+			want:       time.Date(2024, 5, 20, 9, 0, 0, 0, time.Local),
+		},
+		{
+			name:       "next month",
+			expression: "0 12 1 * *",
+			after:      time.Date(2024, 5, 13, 10, 0, 0, 0, time.Local),
+			want:       time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local),
+		},
+		{
+			name:       "sunday as seven",
+			expression: "30 10 * * 7",
+			after:      time.Date(2024, 5, 13, 10, 0, 0, 0, time.Local),
+			want:       time.Date(2024, 5, 19, 10, 30, 0, 0, time.Local),
+		},
+		{
+			name:       "day of month or day of week",
+			expression: "0 9 1 * 1",
+			after:      time.Date(2024, 5, 13, 9, 0, 0, 0, time.Local),
+			want:       time.Date(2024, 5, 20, 9, 0, 0, 0, time.Local),
+		},
+		{
+			name:       "list and range",
+			expression: "0,30 13-14 * 5 *",
+			after:      time.Date(2024, 5, 31, 14, 0, 0, 0, time.Local),
+			want:       time.Date(2024, 5, 31, 14, 30, 0, 0, time.Local),
+		},
+		{
+			name:       "next year",
+			expression: "0 0 1 1 *",
+			after:      time.Date(2024, 5, 13, 0, 0, 0, 0, time.Local),
+			want:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := parseCronExpression(tt.expression)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := (&cronSchedule{expressions: []*cronExpression{expression}}).Next(tt.after)
+
+			if !got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	settings := models.ScheduleSettingsDto{
+		WorkDays:   []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		WorkHours:  &models.TimeRangeDto{From: "09:00", To: "18:00"},
+		LunchBreak: &models.TimeRangeDto{From: "13:00", To: "14:00"},
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		after    time.Time
+		want     time.Time
+	}{
+		{
+			name:     "earliest of the expressions",
+			schedule: "45 17 * * 1-5; 0 11,14 * * 1-5",
+			after:    time.Date(2024, 5, 13, 11, 0, 0, 0, time.Local),
+			want:     time.Date(2024, 5, 13, 14, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "skips the lunch break",
+			schedule: "*/20 * * * *",
+			after:    time.Date(2024, 5, 13, 12, 50, 0, 0, time.Local),
+			want:     time.Date(2024, 5, 13, 13, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "after the lunch break",
+			schedule: "*/20 * * * *",
+			after:    time.Date(2024, 5, 13, 13, 0, 0, 0, time.Local),
+			want:     time.Date(2024, 5, 13, 14, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "skips the weekend",
+			schedule: "0 10 * * *",
+			after:    time.Date(2024, 5, 17, 10, 0, 0, 0, time.Local),
+			want:     time.Date(2024, 5, 20, 10, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "outside of working hours",
+			schedule: "30 20 * * *",
+			after:    time.Date(2024, 5, 13, 10, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "on days off only",
+			schedule: "0 10 * * 6,0",
+			after:    time.Date(2024, 5, 13, 10, 0, 0, 0, time.Local),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expressions, err := parseCronSchedule(tt.schedule)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := (&cronSchedule{workWindow: newWorkWindow(settings), expressions: expressions}).Next(tt.after)
+
+			if !got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronExpressionErrors(t *testing.T) {
+	for _, v := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := parseCronExpression(v)
+
+		if err == nil {
+			t.Errorf("expected an error for %q", v)
+		}
+	}
+}
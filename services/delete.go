@@ -0,0 +1,350 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logDeletionUndoWindow is how long a confirmed deletion can still be undone.
+const logDeletionUndoWindow = 30 * time.Second
+
+func (a *ApiHandler) HandleDeleteLogCommand(userId int64, args string) {
+	date := time.Now()
+
+	if strings.TrimSpace(args) != "" {
+		var err error
+
+		date, err = time.ParseInLocation(time.DateOnly, strings.TrimSpace(args), time.Local)
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Укажите дату в формате ГГГГ-ММ-ДД, например: /%s 2024-05-13. Без аргументов удаляются записи за сегодня", constants.DeleteLogCommand))
+			return
+		}
+	}
+
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	logs = excludePendingDeletions(logs, settings.LogDeletions)
+
+	if len(logs) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("Записей за %s нет", utils.GetOnlyDate(date)))
+		return
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectDeleteLog
+		settings.DeleteLogDate = utils.GetOnlyDate(date)
+		settings.DeleteLogId = ""
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	sort.Slice(logs, func(i, k int) bool {
+		return logs[i].StartWorkTime.Before(logs[k].StartWorkTime)
+	})
+
+	var markup []models.MarkupData
+
+	for _, v := range logs {
+		markup = append(markup, models.MarkupData{
+			Key:   truncateText(fmt.Sprintf("%s %s", formatLogPeriod(v), v.Message), maxButtonTextLength),
+			Value: v.Id,
+		})
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:   userId,
+		Body:     fmt.Sprintf("Выберите запись за %s, которую хотите удалить", utils.GetOnlyDate(date)),
+		Markup:   markup,
+		Controls: []models.MarkupData{{Key: "Отмена", Value: constants.CallbackParamCancel}},
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) HandleCallbackSelectDeleteLog(userId int64, data string) {
+	if data == constants.CallbackParamCancel {
+		a.cancelDeleteLog(userId)
+		return
+	}
+
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, settings.DeleteLogDate, time.Local)
+
+	if err != nil {
+		logrus.Errorf("Failed to parse date: %v", err)
+		return
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	log := findLog(logs, data)
+
+	if log == nil {
+		a.sendMessage(userId, "Запись не найдена, возможно она была удалена")
+		return
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateConfirmDeleteLog
+		settings.DeleteLogId = log.Id
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: userId,
+		Body:   fmt.Sprintf("Удалить запись %s %s %s%s?", settings.DeleteLogDate, formatLogPeriod(*log), log.Message, formatLogLabels(*log)),
+		Markup: []models.MarkupData{
+			{Key: "Удалить", Value: constants.CallbackParamConfirm},
+			{Key: "Отмена", Value: constants.CallbackParamCancel},
+		},
+		Columns: 2,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+// HandleCallbackConfirmDeleteLog does not remove the log right away: the
+// deletion is stored and carried out once the undo window has passed.
+func (a *ApiHandler) HandleCallbackConfirmDeleteLog(userId int64, data string) {
+	if data != constants.CallbackParamConfirm {
+		a.cancelDeleteLog(userId)
+		return
+	}
+
+	var deletion models.LogDeletionDto
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		deletion = models.LogDeletionDto{
+			LogId:    settings.DeleteLogId,
+			Date:     settings.DeleteLogDate,
+			DeleteAt: time.Now().Add(logDeletionUndoWindow),
+		}
+
+		settings.CurrentState = constants.UserStateNone
+		settings.DeleteLogId = ""
+		settings.DeleteLogDate = ""
+
+		if deletion.LogId != "" {
+			settings.LogDeletions = append(settings.LogDeletions, deletion)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if deletion.LogId == "" {
+		return
+	}
+
+	a.scheduleLogDeletion(userId, deletion)
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: userId,
+		Body:   fmt.Sprintf("Запись будет удалена через %d секунд", int(logDeletionUndoWindow.Seconds())),
+		Markup: []models.MarkupData{
+			{Key: "Отменить удаление", Value: constants.CallbackPrefixUndoDelete + deletion.LogId},
+		},
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+// HandleCallbackUndoDeleteLog is accepted in any state, the undo button stays
+// in the chat while the user goes on with other commands.
+func (a *ApiHandler) HandleCallbackUndoDeleteLog(userId int64, data string) {
+	logId := strings.TrimPrefix(data, constants.CallbackPrefixUndoDelete)
+	undone := false
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		var rest []models.LogDeletionDto
+
+		for _, v := range settings.LogDeletions {
+			if v.LogId == logId {
+				undone = true
+				continue
+			}
+
+			rest = append(rest, v)
+		}
+
+		settings.LogDeletions = rest
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if !undone {
+		a.sendMessage(userId, "Отменить удаление уже нельзя")
+		return
+	}
+
+	a.sendMessage(userId, "Удаление отменено, запись сохранена")
+}
+
+// ResumeLogDeletions reschedules the deletions confirmed before a restart,
+// the overdue ones are carried out right away.
+func (a *ApiHandler) ResumeLogDeletions() error {
+	users, err := a.provider.GetUsers()
+
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		for _, v := range user.LogDeletions {
+			a.scheduleLogDeletion(user.UserId, v)
+		}
+	}
+
+	return nil
+}
+
+func (a *ApiHandler) scheduleLogDeletion(userId int64, deletion models.LogDeletionDto) {
+	time.AfterFunc(time.Until(deletion.DeleteAt), func() {
+		a.finishLogDeletion(userId, deletion)
+	})
+}
+
+// finishLogDeletion removes the log unless the deletion has been undone. The
+// deletion is matched by its time too, so a log deleted again after an undo
+// waits for its own window.
+func (a *ApiHandler) finishLogDeletion(userId int64, deletion models.LogDeletionDto) {
+	pending := false
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		var rest []models.LogDeletionDto
+
+		for _, v := range settings.LogDeletions {
+			if v.LogId == deletion.LogId && v.DeleteAt.Equal(deletion.DeleteAt) {
+				pending = true
+				continue
+			}
+
+			rest = append(rest, v)
+		}
+
+		settings.LogDeletions = rest
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if !pending {
+		return
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, deletion.Date, time.Local)
+
+	if err != nil {
+		logrus.Errorf("Failed to parse date: %v", err)
+		return
+	}
+
+	log, err := a.DeleteLog(userId, date, deletion.LogId)
+
+	if errors.Is(err, ErrLogNotFound) {
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to delete log: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Запись %s %s %s удалена", deletion.Date, formatLogPeriod(*log), log.Message))
+}
+
+func (a *ApiHandler) cancelDeleteLog(userId int64) {
+	_, err := a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, "Удаление отменено")
+}
+
+func excludePendingDeletions(logs []models.LogsInfoDto, deletions []models.LogDeletionDto) []models.LogsInfoDto {
+	var rest []models.LogsInfoDto
+
+	for _, v := range logs {
+		pending := false
+
+		for _, deletion := range deletions {
+			if deletion.LogId == v.Id {
+				pending = true
+				break
+			}
+		}
+
+		if !pending {
+			rest = append(rest, v)
+		}
+	}
+
+	return rest
+}
+
+func isDeleteState(state constants.UserState) bool {
+	return state == constants.UserStateSelectDeleteLog ||
+		state == constants.UserStateConfirmDeleteLog ||
+		state == constants.UserStateConfirmDeleteLogs
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+const deleteTestUserId = 1
+
+func newDeleteTestHandler(t *testing.T, deletions ...models.LogDeletionDto) (*ApiHandler, *fakeStorage, *fakeTgClient) {
+	start := time.Date(2024, 5, 13, 10, 0, 0, 0, time.Local)
+	log := models.LogsInfoDto{Id: "log", StartWorkTime: start, EndWorkTime: start.Add(time.Hour), Message: "review"}
+
+	storage := newFakeStorage(models.UserSettingsDto{
+		UserId:        deleteTestUserId,
+		CurrentState:  constants.UserStateConfirmDeleteLog,
+		DeleteLogId:   log.Id,
+		DeleteLogDate: "2024-05-13",
+		LogDeletions:  deletions,
+	})
+
+	err := storage.InsertNewLogRecord(deleteTestUserId, log.EndWorkTime, &log)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tgCli := &fakeTgClient{}
+
+	return NewApiHandler(storage, tgCli, nil, nil, nil, ""), storage, tgCli
+}
+
+func getDeleteTestLogs(t *testing.T, storage *fakeStorage) int {
+	logs, err := storage.GetLogRecords(deleteTestUserId, time.Date(2024, 5, 13, 0, 0, 0, 0, time.Local))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return len(logs)
+}
+
+// confirmDeleteTestLog confirms the deletion and returns it the way the undo
+// timer will see it.
+func confirmDeleteTestLog(t *testing.T, handler *ApiHandler, storage *fakeStorage) models.LogDeletionDto {
+	handler.HandleCallbackConfirmDeleteLog(deleteTestUserId, constants.CallbackParamConfirm)
+
+	settings, err := storage.GetUserSettings(deleteTestUserId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(settings.LogDeletions) != 1 || settings.LogDeletions[0].LogId != "log" {
+		t.Fatalf("got pending deletions %+v", settings.LogDeletions)
+	}
+
+	return settings.LogDeletions[0]
+}
+
+func getLastDeleteTestMessage(tgCli *fakeTgClient) string {
+	tgCli.mu.Lock()
+	defer tgCli.mu.Unlock()
+
+	if len(tgCli.messages) == 0 {
+		return ""
+	}
+
+	return tgCli.messages[len(tgCli.messages)-1].Body
+}
+
+func TestUndoDeleteLogWithinWindow(t *testing.T) {
+	handler, storage, tgCli := newDeleteTestHandler(t)
+	deletion := confirmDeleteTestLog(t, handler, storage)
+
+	if getDeleteTestLogs(t, storage) != 1 {
+		t.Fatal("the log is deleted before the undo window has passed")
+	}
+
+	handler.HandleCallbackUndoDeleteLog(deleteTestUserId, constants.CallbackPrefixUndoDelete+deletion.LogId)
+
+	if message := getLastDeleteTestMessage(tgCli); message != "Удаление отменено, запись сохранена" {
+		t.Errorf("got message %q", message)
+	}
+
+	handler.finishLogDeletion(deleteTestUserId, deletion)
+
+	if getDeleteTestLogs(t, storage) != 1 {
+		t.Error("the undone deletion removed the log")
+	}
+}
+
+func TestUndoDeleteLogAfterWindow(t *testing.T) {
+	handler, storage, tgCli := newDeleteTestHandler(t)
+	deletion := confirmDeleteTestLog(t, handler, storage)
+
+	handler.finishLogDeletion(deleteTestUserId, deletion)
+	handler.HandleCallbackUndoDeleteLog(deleteTestUserId, constants.CallbackPrefixUndoDelete+deletion.LogId)
+
+	if message := getLastDeleteTestMessage(tgCli); message != "Отменить удаление уже нельзя" {
+		t.Errorf("got message %q", message)
+	}
+
+	if getDeleteTestLogs(t, storage) != 0 {
+		t.Error("the log is kept after a late undo")
+	}
+}
+
+func TestDeleteLogFinishedAfterWindow(t *testing.T) {
+	handler, storage, tgCli := newDeleteTestHandler(t)
+	deletion := confirmDeleteTestLog(t, handler, storage)
+
+	if !deletion.DeleteAt.After(time.Now().Add(logDeletionUndoWindow - time.Second)) {
+		t.Errorf("got deletion at %s, want in %s", deletion.DeleteAt, logDeletionUndoWindow)
+	}
+
+	handler.finishLogDeletion(deleteTestUserId, deletion)
+
+	if getDeleteTestLogs(t, storage) != 0 {
+		t.Error("the log is kept after the undo window")
+	}
+
+	settings, err := storage.GetUserSettings(deleteTestUserId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(settings.LogDeletions) != 0 {
+		t.Errorf("got pending deletions %+v after the window", settings.LogDeletions)
+	}
+
+	if message := getLastDeleteTestMessage(tgCli); !strings.HasSuffix(message, "review удалена") {
+		t.Errorf("got message %q", message)
+	}
+}
+
+func TestResumeLogDeletions(t *testing.T) {
+	tests := []struct {
+		name     string
+		deleteAt time.Time
+		logs     int
+	}{
+		{name: "overdue", deleteAt: time.Now().Add(-time.Minute)},
+		{name: "still pending", deleteAt: time.Now().Add(time.Hour), logs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The deletion was confirmed before the restart, the new handler
+			// only knows it from the settings.
+			handler, storage, _ := newDeleteTestHandler(t, models.LogDeletionDto{LogId: "log", Date: "2024-05-13", DeleteAt: tt.deleteAt})
+
+			err := handler.ResumeLogDeletions()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+
+			for getDeleteTestLogs(t, storage) != tt.logs && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if logs := getDeleteTestLogs(t, storage); logs != tt.logs {
+				t.Errorf("got %d logs, want %d", logs, tt.logs)
+			}
+		})
+	}
+}
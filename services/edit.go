@@ -0,0 +1,329 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var editFieldTitles = map[string]string{
+	constants.CallbackParamEditMessage: "Комментарий",
+	constants.CallbackParamEditStart:   "Начало",
+	constants.CallbackParamEditEnd:     "Конец",
+}
+
+func (a *ApiHandler) HandleEditLogCommand(userId int64, args string) {
+	date := time.Now()
+
+	if strings.TrimSpace(args) != "" {
+		var err error
+
+		date, err = time.ParseInLocation(time.DateOnly, strings.TrimSpace(args), time.Local)
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Укажите дату в формате ГГГГ-ММ-ДД, например: /%s 2024-05-13. Без аргументов редактируются записи за сегодня", constants.EditLogCommand))
+			return
+		}
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	if len(logs) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("Записей за %s нет", utils.GetOnlyDate(date)))
+		return
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectEditLog
+		settings.EditLogDate = utils.GetOnlyDate(date)
+		settings.EditLogId = ""
+		settings.EditField = ""
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	sort.Slice(logs, func(i, k int) bool {
+		return logs[i].StartWorkTime.Before(logs[k].StartWorkTime)
+	})
+
+	var markup []models.MarkupData
+
+	for _, v := range logs {
+		markup = append(markup, models.MarkupData{
+			Key:   truncateText(fmt.Sprintf("%s %s", formatLogPeriod(v), v.Message), maxButtonTextLength),
+			Value: v.Id,
+		})
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:   userId,
+		Body:     fmt.Sprintf("Выберите запись за %s, которую хотите изменить", utils.GetOnlyDate(date)),
+		Markup:   markup,
+		Controls: []models.MarkupData{{Key: "Отмена", Value: constants.CallbackParamCancel}},
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) HandleCallbackSelectEditLog(userId int64, data string) {
+	if data == constants.CallbackParamCancel {
+		a.cancelEdit(userId)
+		return
+	}
+
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	log, _, err := a.getEditedLog(settings, data)
+
+	if err != nil {
+		logrus.Errorf("Failed to get edited log: %v", err)
+		return
+	}
+
+	if log == nil {
+		a.sendMessage(userId, "Запись не найдена, возможно она была удалена")
+		return
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectEditField
+		settings.EditLogId = log.Id
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	var markup []models.MarkupData
+
+	for _, field := range []string{constants.CallbackParamEditMessage, constants.CallbackParamEditStart, constants.CallbackParamEditEnd} {
+		markup = append(markup, models.MarkupData{Key: editFieldTitles[field], Value: field})
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:   userId,
+		Body:     fmt.Sprintf("Запись %s %s%s. Что изменить?", formatLogPeriod(*log), log.Message, formatLogLabels(*log)),
+		Markup:   markup,
+		Controls: []models.MarkupData{{Key: "Отмена", Value: constants.CallbackParamCancel}},
+		Columns:  len(markup),
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) HandleCallbackSelectEditField(userId int64, data string) {
+	if _, exist := editFieldTitles[data]; !exist {
+		a.cancelEdit(userId)
+		return
+	}
+
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateEnterEditValue
+		settings.EditField = data
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	log, logs, err := a.getEditedLog(settings, settings.EditLogId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get edited log: %v", err)
+		return
+	}
+
+	if log == nil {
+		a.cancelEdit(userId)
+		return
+	}
+
+	prevEnd, nextStart := getNeighbourBounds(logs, *log)
+
+	switch data {
+	case constants.CallbackParamEditMessage:
+		a.sendMessage(userId, fmt.Sprintf("Текущий комментарий: %s\nВведите новый комментарий", log.Message))
+	case constants.CallbackParamEditStart:
+		a.sendMessage(userId, fmt.Sprintf("Введите новое время начала в формате ЧЧ:ММ, %s", formatTimeBounds(prevEnd, log.EndWorkTime)))
+	case constants.CallbackParamEditEnd:
+		a.sendMessage(userId, fmt.Sprintf("Введите новое время окончания в формате ЧЧ:ММ, %s", formatTimeBounds(log.StartWorkTime, nextStart)))
+	}
+}
+
+// HandleEditLogValue applies the typed value. On a validation error the user
+// stays in the same step and can type the value again.
+func (a *ApiHandler) HandleEditLogValue(userId int64, value string) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	log, logs, err := a.getEditedLog(settings, settings.EditLogId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get edited log: %v", err)
+		return
+	}
+
+	if log == nil {
+		a.sendMessage(userId, "Запись не найдена, возможно она была удалена")
+		a.cancelEdit(userId)
+		return
+	}
+
+	prevEnd, nextStart := getNeighbourBounds(logs, *log)
+	value = strings.TrimSpace(value)
+
+	switch settings.EditField {
+	case constants.CallbackParamEditMessage:
+		if value == "" {
+			a.sendMessage(userId, "Комментарий не может быть пустым")
+			return
+		}
+
+		log.Message = value
+	case constants.CallbackParamEditStart:
+		startTime, ok := parseEditTime(value, log.StartWorkTime)
+
+		if !ok || !startTime.Before(log.EndWorkTime) || startTime.Before(prevEnd) {
+			a.sendMessage(userId, fmt.Sprintf("Некорректное время начала, %s", formatTimeBounds(prevEnd, log.EndWorkTime)))
+			return
+		}
+
+		log.StartWorkTime = startTime
+	case constants.CallbackParamEditEnd:
+		endTime, ok := parseEditTime(value, log.EndWorkTime)
+
+		if !ok || !endTime.After(log.StartWorkTime) || (!nextStart.IsZero() && endTime.After(nextStart)) {
+			a.sendMessage(userId, fmt.Sprintf("Некорректное время окончания, %s", formatTimeBounds(log.StartWorkTime, nextStart)))
+			return
+		}
+
+		log.EndWorkTime = endTime
+	default:
+		a.cancelEdit(userId)
+		return
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, settings.EditLogDate, time.Local)
+
+	if err != nil {
+		logrus.Errorf("Failed to parse date: %v", err)
+		return
+	}
+
+	err = a.UpdateLog(userId, date, log)
+
+	if errors.Is(err, ErrInvalidLog) || errors.Is(err, ErrLogNotFound) {
+		a.sendMessage(userId, fmt.Sprintf("Не удалось изменить запись: %v", err))
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to update log: %v", err)
+		return
+	}
+
+	_, err = a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Запись обновлена: %s %s%s", formatLogPeriod(*log), log.Message, formatLogLabels(*log)))
+}
+
+func (a *ApiHandler) cancelEdit(userId int64) {
+	_, err := a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, "Редактирование отменено")
+}
+
+// getEditedLog returns the log being edited together with the other logs of
+// its date, the log is nil when it no longer exists.
+func (a *ApiHandler) getEditedLog(settings *models.UserSettingsDto, logId string) (*models.LogsInfoDto, []models.LogsInfoDto, error) {
+	date, err := time.ParseInLocation(time.DateOnly, settings.EditLogDate, time.Local)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logs, err := a.provider.GetLogRecords(settings.UserId, date)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return findLog(logs, logId), logs, nil
+}
+
+// parseEditTime puts the typed HH:MM on the day of the current value.
+func parseEditTime(value string, current time.Time) (time.Time, bool) {
+	offset, err := utils.ParseDayTime(value)
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return utils.GetStartOfDay(current).Add(offset), true
+}
+
+func formatTimeBounds(from time.Time, to time.Time) string {
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		return fmt.Sprintf("допустимо с %s по %s", from.Format("15:04"), to.Format("15:04"))
+	case !from.IsZero():
+		return fmt.Sprintf("не раньше %s", from.Format("15:04"))
+	case !to.IsZero():
+		return fmt.Sprintf("не позже %s", to.Format("15:04"))
+	default:
+		return "без ограничений"
+	}
+}
+
+func formatLogPeriod(log models.LogsInfoDto) string {
+	return fmt.Sprintf("%s-%s", log.StartWorkTime.Format("15:04"), log.EndWorkTime.Format("15:04"))
+}
+
+func isEditState(state constants.UserState) bool {
+	return state == constants.UserStateSelectEditLog ||
+		state == constants.UserStateSelectEditField ||
+		state == constants.UserStateEnterEditValue
+}
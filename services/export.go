@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxExportDays = 366
+	// utf8Bom makes spreadsheet editors detect the encoding of the Cyrillic text.
+	utf8Bom = "\uFEFF"
+)
+
+var csvHeader = []string{"date", "start", "end", "duration", "duration_minutes", "message", "project", "tags", "issue_key"}
+
+func (a *ApiHandler) HandleExportCommand(userId int64, args string) {
+	from, to, err := parseDateRangeArg(args, time.Now())
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Укажите период в формате ГГГГ-ММ-ДД, например: /%s 2024-05-01 2024-05-31 или /%s 2024-05-13. Без аргументов выгружается текущий месяц",
+			constants.ExportCommand, constants.ExportCommand))
+		return
+	}
+
+	days, err := a.getLogsByRange(userId, from, to)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	if len(days) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("Логов за период %s - %s не найдено", utils.GetOnlyDate(from), utils.GetOnlyDate(to)))
+		return
+	}
+
+	data, err := buildLogsCsv(days)
+
+	if err != nil {
+		logrus.Errorf("Failed to build csv: %v", err)
+		return
+	}
+
+	err = a.tgClient.SendDocument(&models.SendDocumentRequest{
+		ChatId:   userId,
+		FileName: fmt.Sprintf("logs_%s_%s.csv", utils.GetOnlyDate(from), utils.GetOnlyDate(to)),
+		Data:     data,
+		Caption:  fmt.Sprintf("Логи за период %s - %s", utils.GetOnlyDate(from), utils.GetOnlyDate(to)),
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send document: %v", err)
+	}
+}
+
+func buildLogsCsv(days []dayLogs) ([]byte, error) {
+	buf := bytes.NewBufferString(utf8Bom)
+	writer := csv.NewWriter(buf)
+
+	err := writer.Write(csvHeader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, day := range days {
+		for _, v := range day.logs {
+			delta := v.EndWorkTime.Sub(v.StartWorkTime)
+
+			err = writer.Write([]string{
+				utils.GetOnlyDate(day.date),
+				utils.GetOnlyTime(v.StartWorkTime),
+				utils.GetOnlyTime(v.EndWorkTime),
+				fmt.Sprintf("%d:%02d", int(delta.Hours()), int(delta.Minutes())%60),
+				strconv.Itoa(int(delta.Minutes())),
+				escapeCsvCell(v.Message),
+				escapeCsvCell(v.Project),
+				escapeCsvCell(strings.Join(v.Tags, ";")),
+				escapeCsvCell(v.IssueKey),
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return buf.Bytes(), writer.Error()
+}
+
+// escapeCsvCell keeps spreadsheet editors from running a user text as a
+// formula by prefixing it with an apostrophe.
+func escapeCsvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// parseDateRangeArg reads "from to", a single day or nothing, the latter
+// standing for the current month up to today.
+func parseDateRangeArg(args string, now time.Time) (time.Time, time.Time, error) {
+	fields := strings.Fields(args)
+
+	if len(fields) == 0 {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), utils.GetStartOfDay(now), nil
+	}
+
+	if len(fields) > 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected at most two dates, got %d", len(fields))
+	}
+
+	from, err := time.ParseInLocation(time.DateOnly, fields[0], now.Location())
+
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	to := from
+
+	if len(fields) == 2 {
+		to, err = time.ParseInLocation(time.DateOnly, fields[1], now.Location())
+
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("range end %s is before its start %s", fields[1], fields[0])
+	}
+
+	if to.Sub(from) > maxExportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range is longer than %d days", maxExportDays)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/csv"
+	"logs-aggregator-bot/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDateRangeArg(t *testing.T) {
+	now := time.Date(2024, 5, 13, 15, 0, 0, 0, time.Local)
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		args    string
+		from    time.Time
+		to      time.Time
+		wantErr bool
+	}{
+		{args: "", from: day(5, 1), to: day(5, 13)},
+		{args: "2024-05-10", from: day(5, 10), to: day(5, 10)},
+		{args: " 2024-04-01   2024-04-30 ", from: day(4, 1), to: day(4, 30)},
+		{args: "2024-05-10 2024-05-01", wantErr: true},
+		{args: "2024-05-01 2024-05-02 2024-05-03", wantErr: true},
+		{args: "13.05.2024", wantErr: true},
+		{args: "2023-01-01 2024-05-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			from, to, err := parseDateRangeArg(tt.args, now)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("got %s - %s, want %s - %s", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestBuildLogsCsv(t *testing.T) {
+	date := time.Date(2024, 5, 13, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		log  models.LogsInfoDto
+		want []string
+	}{
+		{
+			name: "plain",
+			log: models.LogsInfoDto{
+				StartWorkTime: date.Add(9 * time.Hour),
+				EndWorkTime:   date.Add(10*time.Hour + 30*time.Minute),
+				Message:       "PROJ-1 ревью, часть 1",
+				Project:       "bot",
+				Tags:          []string{"review", "backend"},
+				IssueKey:      "PROJ-1",
+			},
+			want: []string{"2024-05-13", "09:00:00", "10:30:00", "1:30", "90", "PROJ-1 ревью, часть 1", "bot", "review;backend", "PROJ-1"},
+		},
+		{
+			name: "formulas",
+			log: models.LogsInfoDto{
+				StartWorkTime: date.Add(11 * time.Hour),
+				EndWorkTime:   date.Add(11*time.Hour + 5*time.Minute),
+				Message:       "=HYPERLINK(\"http://example.com\")",
+				Project:       "+project",
+				Tags:          []string{"-tag", "ok"},
+				IssueKey:      "@key",
+			},
+			want: []string{"2024-05-13", "11:00:00", "11:05:00", "0:05", "5", "'=HYPERLINK(\"http://example.com\")", "'+project", "'-tag;ok", "'@key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := buildLogsCsv([]dayLogs{{date: date, logs: []models.LogsInfoDto{tt.log}}})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text, found := strings.CutPrefix(string(data), utf8Bom)
+
+			if !found {
+				t.Error("got no byte order mark")
+			}
+
+			records, err := csv.NewReader(strings.NewReader(text)).ReadAll()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(records) != 2 || !reflect.DeepEqual(records[0], csvHeader) || !reflect.DeepEqual(records[1], tt.want) {
+				t.Errorf("got %q, want header and %q", records, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleExportCommand(t *testing.T) {
+	storage := newFakeStorage(models.UserSettingsDto{UserId: 1})
+	tgCli := &fakeTgClient{}
+	handler := NewApiHandler(storage, tgCli, nil, nil, nil, "bot")
+
+	for _, v := range []struct {
+		day     int
+		hour    int
+		message string
+	}{{13, 11, "second"}, {13, 9, "first"}, {14, 9, "third"}, {20, 9, "outside"}} {
+		start := time.Date(2024, 5, v.day, v.hour, 0, 0, 0, time.Local)
+		log := models.LogsInfoDto{Id: v.message, StartWorkTime: start, EndWorkTime: start.Add(time.Hour), Message: v.message}
+
+		err := storage.InsertNewLogRecord(1, log.EndWorkTime, &log)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler.HandleExportCommand(1, "2024-05-01 2024-05-15")
+
+	if len(tgCli.documents) != 1 {
+		t.Fatalf("got %d documents, want 1", len(tgCli.documents))
+	}
+
+	document := tgCli.documents[0]
+
+	if document.FileName != "logs_2024-05-01_2024-05-15.csv" {
+		t.Errorf("got file name %q", document.FileName)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(document.Data), utf8Bom))).ReadAll()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+
+	for _, v := range records[1:] {
+		messages = append(messages, v[5])
+	}
+
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("got messages %q, want %q", messages, want)
+	}
+}
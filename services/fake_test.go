@@ -0,0 +1,177 @@
+package services
+
+import (
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/provider"
+	"logs-aggregator-bot/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fakeStorage keeps the settings and the logs in memory. Only the methods the
+// tests need are implemented, the others panic on the nil embedded provider.
+type fakeStorage struct {
+	provider.StorageProvider
+	mu       sync.Mutex
+	settings map[int64]models.UserSettingsDto
+	logs     map[int64]map[string][]models.LogsInfoDto
+}
+
+func newFakeStorage(settings ...models.UserSettingsDto) *fakeStorage {
+	storage := &fakeStorage{
+		settings: map[int64]models.UserSettingsDto{},
+		logs:     map[int64]map[string][]models.LogsInfoDto{},
+	}
+
+	for _, v := range settings {
+		storage.settings[v.UserId] = v
+	}
+
+	return storage
+}
+
+func (f *fakeStorage) GetUsers() ([]models.UserSettingsDto, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var users []models.UserSettingsDto
+
+	for _, v := range f.settings {
+		users = append(users, v)
+	}
+
+	return users, nil
+}
+
+func (f *fakeStorage) GetUserSettings(userId int64) (*models.UserSettingsDto, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	settings, exist := f.settings[userId]
+
+	if !exist {
+		return nil, provider.ErrUserNotFound
+	}
+
+	return &settings, nil
+}
+
+func (f *fakeStorage) SetUserSettings(settings *models.UserSettingsDto) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.settings[settings.UserId] = *settings
+	return nil
+}
+
+func (f *fakeStorage) UpdateUserSettings(userId int64, update func(settings *models.UserSettingsDto) error) (*models.UserSettingsDto, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	settings, exist := f.settings[userId]
+
+	if !exist {
+		return nil, provider.ErrUserNotFound
+	}
+
+	err := update(&settings)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.settings[userId] = settings
+	return &settings, nil
+}
+
+func (f *fakeStorage) InsertNewLogRecord(userId int64, date time.Time, log *models.LogsInfoDto) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.logs[userId] == nil {
+		f.logs[userId] = map[string][]models.LogsInfoDto{}
+	}
+
+	f.logs[userId][utils.GetOnlyDate(date)] = append(f.logs[userId][utils.GetOnlyDate(date)], *log)
+	return nil
+}
+
+func (f *fakeStorage) GetLogRecords(userId int64, date time.Time) ([]models.LogsInfoDto, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]models.LogsInfoDto(nil), f.logs[userId][utils.GetOnlyDate(date)]...), nil
+}
+
+func (f *fakeStorage) GetDatesWithLogs(userId int64) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var dates []string
+
+	for date, logs := range f.logs[userId] {
+		if len(logs) > 0 {
+			dates = append(dates, date)
+		}
+	}
+
+	sort.Strings(dates)
+	return dates, nil
+}
+
+func (f *fakeStorage) UpdateLogSyncStatus(userId int64, log *models.LogsInfoDto) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, logs := range f.logs[userId] {
+		for i := range logs {
+			if logs[i].Id == log.Id {
+				logs[i].JiraWorklogId = log.JiraWorklogId
+				logs[i].JiraIssueKey = log.JiraIssueKey
+				logs[i].SyncStatus = log.SyncStatus
+				logs[i].SyncError = log.SyncError
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) DeleteLogRecord(userId int64, date string, logId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var logs []models.LogsInfoDto
+
+	for _, v := range f.logs[userId][date] {
+		if v.Id != logId {
+			logs = append(logs, v)
+		}
+	}
+
+	f.logs[userId][date] = logs
+	return nil
+}
+
+type fakeTgClient struct {
+	mu        sync.Mutex
+	messages  []models.SendNotificationRequest
+	documents []models.SendDocumentRequest
+}
+
+func (f *fakeTgClient) SendMessage(req *models.SendNotificationRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, *req)
+	return nil
+}
+
+func (f *fakeTgClient) SendDocument(req *models.SendDocumentRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.documents = append(f.documents, *req)
+	return nil
+}
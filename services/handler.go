@@ -2,40 +2,51 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"logs-aggregator-bot/constants"
 	"logs-aggregator-bot/models"
 	"logs-aggregator-bot/provider"
 	"logs-aggregator-bot/utils"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
+// maxMessageLength stays below the Telegram limit of 4096 characters, which
+// counts the characters outside of the basic plane twice.
+const maxMessageLength = 4000
+
 type ApiHandler struct {
-	provider      *provider.JsonStorageProvider
-	tgClient      tgClient
-	scheduler     *SchedulerService
-	doneChan      chan<- struct{}
-	cachedMessage string
+	provider  provider.StorageProvider
+	tgClient  tgClient
+	scheduler *SchedulerService
+	jira      *JiraSyncService
+	webhooks  *WebhookService
+	botName   string
+	startsMu  sync.Mutex
+	starts    map[int64]time.Time
 }
 
-func NewApiHandler(provider *provider.JsonStorageProvider, tgClient tgClient, scheduler *SchedulerService) *ApiHandler {
-	return &ApiHandler{provider: provider, tgClient: tgClient, scheduler: scheduler, cachedMessage: ""}
+func NewApiHandler(provider provider.StorageProvider, tgClient tgClient, scheduler *SchedulerService, jira *JiraSyncService, webhooks *WebhookService, botName string) *ApiHandler {
+	return &ApiHandler{provider: provider, tgClient: tgClient, scheduler: scheduler, jira: jira, webhooks: webhooks, botName: botName, starts: map[int64]time.Time{}}
 }
 
-func (a *ApiHandler) HandleStartWorkDayCommand() {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleStartWorkDayCommand(userId int64) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("Failed to get user settings: %v", err)
 		return
 	}
 
-	if settings.WorkStarted.Day() == time.Now().Day() {
-		fmt.Println("Today you used this")
+	workDayOpen := isWorkDayOpen(settings, time.Now())
+
+	if workDayOpen && a.scheduler.IsRunning(userId) {
 		err = a.tgClient.SendMessage(&models.SendNotificationRequest{
 			ChatId: settings.UserId,
 			Body:   "Вы уже начали свой рабочий день",
@@ -48,31 +59,40 @@ func (a *ApiHandler) HandleStartWorkDayCommand() {
 		return
 	}
 
-	if a.doneChan != nil {
-		close(a.doneChan)
-	}
+	err = a.scheduler.Start(context.TODO(), userId)
 
-	doneChan := make(chan struct{})
-	a.doneChan = doneChan
+	if err != nil {
+		logrus.Errorf("Failed to start work day: %v", err)
+		return
+	}
 
-	go a.scheduler.Start(context.TODO(), doneChan)
+	if workDayOpen {
+		a.sendMessage(userId, fmt.Sprintf("Рабочий день, начатый в %s, продолжен", utils.GetOnlyTime(settings.WorkStarted)))
+	}
 }
 
-func (a *ApiHandler) HandleStopWorkDayCommand() {
-	a.doneChan <- struct{}{}
+func (a *ApiHandler) HandleStopWorkDayCommand(userId int64) {
+	err := a.scheduler.FinishWorkDay(userId, time.Now())
+
+	if errors.Is(err, ErrWorkDayNotStarted) {
+		a.sendMessage(userId, "Рабочий день не начат")
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to finish work day: %v", err)
+	}
 }
 
-func (a *ApiHandler) HandleDeleteLogsCommand() {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleDeleteLogsCommand(userId int64) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("Failed to get user settings: %v", err)
 		return
 	}
 
-	settings.CurrentState = constants.UserStateSelectLogsToDelete
-
-	dates, err := a.provider.GetDatesWithLogs()
+	dates, err := a.provider.GetDatesWithLogs(userId)
 
 	if err != nil {
 		logrus.Errorf("Failed to get dates: %v", err)
@@ -92,35 +112,18 @@ func (a *ApiHandler) HandleDeleteLogsCommand() {
 		return
 	}
 
-	err = a.provider.SetUserSettings(settings)
+	settings, err = a.setUserState(userId, constants.UserStateSelectLogsToDelete)
 
 	if err != nil {
 		logrus.Errorf("Failed to set user settings: %v", err)
 		return
 	}
 
-	var markup []models.MarkupData
-
-	for i, date := range dates {
-		if i == 5 {
-			break
-		}
-
-		markup = append(markup, models.MarkupData{
-			Key:   date,
-			Value: date,
-		})
-	}
-
-	markup = append(markup, models.MarkupData{
-		Key:   "Завершить",
-		Value: constants.CallbackStopDeleteLogs,
-	})
-
 	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
-		ChatId: settings.UserId,
-		Body:   "Выберите елементы для их удаления",
-		Markup: markup,
+		ChatId:   settings.UserId,
+		Body:     "Выберите день для удаления, дни с логами отмечены точкой",
+		Controls: []models.MarkupData{{Key: "Завершить", Value: constants.CallbackStopDeleteLogs}},
+		Calendar: newDatesCalendar(dates),
 	})
 
 	if err != nil {
@@ -128,8 +131,8 @@ func (a *ApiHandler) HandleDeleteLogsCommand() {
 	}
 }
 
-func (a *ApiHandler) HandleCallbackSelectLogType(data string) {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleCallbackSelectLogType(userId int64, data string) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("Failed to get user settings: %v", err)
@@ -138,16 +141,14 @@ func (a *ApiHandler) HandleCallbackSelectLogType(data string) {
 
 	switch data {
 	case constants.CallbackParamContinueOldLog:
-		settings.CurrentState = constants.UserStateSelectOldLogDate
-
-		err = a.provider.SetUserSettings(settings)
+		settings, err = a.setUserState(userId, constants.UserStateSelectOldLogDate)
 
 		if err != nil {
 			logrus.Errorf("Failed to set user settings: %v", err)
 			return
 		}
 
-		logs, err := a.provider.GetLogRecords(settings.WorkStarted)
+		logs, err := a.provider.GetLogRecords(userId, settings.WorkStarted)
 
 		if err != nil {
 			logrus.Errorf("Failed to get logs: %v", err)
@@ -155,37 +156,11 @@ func (a *ApiHandler) HandleCallbackSelectLogType(data string) {
 		}
 
 		lastLog := getLastLog(logs)
-		dateIntervals := utils.GetInterval(utils.RoundTimeToMinutes(lastLog.EndWorkTime), settings.NeedWorkLogTo, time.Minute*10)
-		var markup []models.MarkupData
-
-		for _, v := range dateIntervals {
-			markup = append(markup, models.MarkupData{
-				Key:   utils.GetOnlyTime(v),
-				Value: fmt.Sprint(v.UnixMilli()),
-			})
-		}
-
-		markup = append(markup, models.MarkupData{
-			Key:   utils.GetOnlyTime(settings.NeedWorkLogTo),
-			Value: fmt.Sprint(settings.NeedWorkLogTo.UnixMilli()),
-		})
-
-		err = a.tgClient.SendMessage(&models.SendNotificationRequest{
-			ChatId: settings.UserId,
-			Body:   fmt.Sprintf("Выберите время, по которую вы продолжали задачу %s", lastLog.Message),
-			Markup: markup,
-		})
-
-		if err != nil {
-			logrus.Errorf("Failed to send message: %v", err)
-			return
-		}
+		a.sendLogTimeSelection(settings, fmt.Sprintf("Укажите время, по которое вы продолжали задачу %s", lastLog.Message))
 	}
 
 	if data == constants.CallbackParamCreateNewLog {
-		settings.CurrentState = constants.UserStateSelectNewLogMessage
-
-		err = a.provider.SetUserSettings(settings)
+		settings, err = a.setUserState(userId, constants.UserStateSelectNewLogMessage)
 
 		if err != nil {
 			logrus.Errorf("Failed to set user settings: %v", err)
@@ -204,44 +179,104 @@ func (a *ApiHandler) HandleCallbackSelectLogType(data string) {
 	}
 }
 
-func (a *ApiHandler) HandleDeleteCallbackParam(data string) bool {
+func (a *ApiHandler) HandleDeleteCallbackParam(userId int64, data string) {
 	if data == constants.CallbackStopDeleteLogs {
-		settings, err := a.provider.GetUserSettings()
+		_, err := a.setUserState(userId, constants.UserStateNone)
 
 		if err != nil {
-			logrus.Errorf("Failed to get user settings: %v", err)
-			return false
+			logrus.Errorf("Failed to set user settings: %v", err)
 		}
 
-		settings.CurrentState = constants.UserStateNone
-		err = a.provider.SetUserSettings(settings)
+		return
+	}
 
-		if err != nil {
-			logrus.Errorf("Failed to set user settings: %v", err)
-			return false
-		}
+	date, err := time.ParseInLocation(time.DateOnly, data, time.Local)
+
+	if err != nil {
+		logrus.Errorf("Failed to parse date: %v", err)
+		return
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	if len(logs) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("Логов за %s нет, возможно они уже удалены", data))
+		return
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateConfirmDeleteLogs
+		settings.DeleteLogDate = data
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: userId,
+		Body: fmt.Sprintf("Удалить логи за %s (записей: %d)? Они будут храниться в корзине %d дней, вернуть их можно командой /%s",
+			data, len(logs), int(trashRetention.Hours()/24), constants.RestoreCommand),
+		Markup: []models.MarkupData{
+			{Key: "Удалить", Value: constants.CallbackParamConfirm},
+			{Key: "Отмена", Value: constants.CallbackParamCancel},
+		},
+		Columns: 2,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+// HandleCallbackConfirmDeleteLogs moves the chosen day to the trash and returns
+// to the date selection, so the user can pick the next day to delete.
+func (a *ApiHandler) HandleCallbackConfirmDeleteLogs(userId int64, data string) bool {
+	date := ""
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		date = settings.DeleteLogDate
+		settings.CurrentState = constants.UserStateSelectLogsToDelete
+		settings.DeleteLogDate = ""
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return false
+	}
+
+	if data != constants.CallbackParamConfirm || date == "" {
 		return false
 	}
 
-	err := a.provider.DeleteLogsByDate(data)
+	err = a.TrashLogsByDate(userId, date)
 
 	if err != nil {
-		logrus.Errorf("failed delete logs: %v", err)
+		logrus.Errorf("Failed to trash logs: %v", err)
 		return false
 	}
 
+	a.sendMessage(userId, fmt.Sprintf("Логи за %s перемещены в корзину", date))
 	return true
 }
 
-func (a *ApiHandler) HandleCallbackSelectOldLogDate(data string) {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleCallbackSelectOldLogDate(userId int64, data string) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("Failed to get user settings: %v", err)
 		return
 	}
 
-	logs, err := a.provider.GetLogRecords(settings.WorkStarted)
+	logs, err := a.provider.GetLogRecords(userId, settings.WorkStarted)
 
 	if err != nil {
 		logrus.Errorf("Failed to get logs: %v", err)
@@ -259,7 +294,7 @@ func (a *ApiHandler) HandleCallbackSelectOldLogDate(data string) {
 	parsedTime := time.UnixMilli(parsedLong)
 
 	oldLog.EndWorkTime = parsedTime
-	err = a.provider.UpdateLogRecord(&oldLog)
+	err = a.updateLog(userId, &oldLog)
 
 	if err != nil {
 		logrus.Errorf("Failed to update old log record: %v", err)
@@ -267,9 +302,7 @@ func (a *ApiHandler) HandleCallbackSelectOldLogDate(data string) {
 	}
 
 	if settings.NeedWorkLogTo.Round(time.Second).Compare(parsedTime.Round(time.Second)) > 0 {
-		settings.CurrentState = constants.UserStateSelectNewLogMessage
-
-		err = a.provider.SetUserSettings(settings)
+		settings, err = a.setUserState(userId, constants.UserStateSelectNewLogMessage)
 
 		if err != nil {
 			logrus.Errorf("Failed to set user settings: %v", err)
@@ -286,86 +319,67 @@ func (a *ApiHandler) HandleCallbackSelectOldLogDate(data string) {
 			return
 		}
 	} else {
-		settings.CurrentState = constants.UserStateNone
-		err = a.provider.SetUserSettings(settings)
-
-		if err != nil {
-			logrus.Errorf("Failed to set user settings: %v", err)
-		}
+		a.completeWorkLog(userId)
 	}
-
 }
 
-func (a *ApiHandler) HandleSelectNewLogMessage(message string) {
-	settings, err := a.provider.GetUserSettings()
-
-	if err != nil {
-		logrus.Errorf("failed to get user settings: %v", err)
+func (a *ApiHandler) HandleSelectNewLogMessage(userId int64, message string) {
+	if a.suggestIssueMessage(userId, message) {
 		return
 	}
 
-	settings.CurrentState = constants.UserStateSelectNewLogDate
+	a.startLogDraft(userId, message)
+}
 
-	err = a.provider.SetUserSettings(settings)
+// startLogDraft walks the log draft through project, tags and time selection,
+// skipping the steps the user has nothing to choose from.
+func (a *ApiHandler) startLogDraft(userId int64, message string) {
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectProject
+		settings.PendingMessage = message
+		settings.PendingProject = ""
+		settings.PendingTags = nil
+		settings.PendingHint = ""
+
+		if len(settings.Projects) == 0 {
+			settings.CurrentState = constants.UserStateSelectTags
+		}
 
-	if err != nil {
-		logrus.Errorf("Failed to set user settings: %v", err)
-		return
-	}
+		if len(settings.Projects) == 0 && len(settings.Tags) == 0 {
+			settings.CurrentState = constants.UserStateSelectNewLogDate
+		}
 
-	logs, err := a.provider.GetLogRecords(settings.WorkStarted)
+		return nil
+	})
 
 	if err != nil {
-		logrus.Errorf("Failed to get logs: %v", err)
+		logrus.Errorf("Failed to set user settings: %v", err)
 		return
 	}
 
-	a.cachedMessage = message
-
-	var intervals []time.Time
-
-	if len(logs) > 0 {
-		lastLog := getLastLog(logs)
-		intervals = utils.GetInterval(lastLog.EndWorkTime, settings.NeedWorkLogTo, time.Minute*10)
-	} else {
-		intervals = utils.GetInterval(settings.WorkStarted, settings.NeedWorkLogTo, time.Minute*10)
-	}
-
-	var markup []models.MarkupData
-
-	for _, v := range intervals {
-		markup = append(markup, models.MarkupData{
-			Key:   utils.GetOnlyTime(v),
-			Value: fmt.Sprint(v.UnixMilli()),
-		})
+	switch settings.CurrentState {
+	case constants.UserStateSelectProject:
+		a.sendProjectSelection(settings)
+	case constants.UserStateSelectTags:
+		a.sendTagsSelection(settings)
+	default:
+		a.sendNewLogTimeSelection(settings)
 	}
+}
 
-	markup = append(markup, models.MarkupData{
-		Key:   utils.GetOnlyTime(settings.NeedWorkLogTo),
-		Value: fmt.Sprint(settings.NeedWorkLogTo.UnixMilli()),
-	})
-
-	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
-		ChatId: settings.UserId,
-		Body:   fmt.Sprintf("Выберите время, по которую вы продолжали делать задачу %s", message),
-		Markup: markup,
-	})
-
-	if err != nil {
-		logrus.Errorf("Failed to send message: %v", err)
-		return
-	}
+func (a *ApiHandler) sendNewLogTimeSelection(settings *models.UserSettingsDto) {
+	a.sendLogTimeSelection(settings, fmt.Sprintf("Укажите время, по которое вы продолжали делать задачу %s", settings.PendingMessage))
 }
 
-func (a *ApiHandler) HandleCallbackSelectNewLogDate(data string) {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleCallbackSelectNewLogDate(userId int64, data string) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("failed to get user settings: %v", err)
 		return
 	}
 
-	logs, err := a.provider.GetLogRecords(settings.WorkStarted)
+	logs, err := a.provider.GetLogRecords(userId, settings.WorkStarted)
 
 	if err != nil {
 		logrus.Errorf("Failed to get logs: %v", err)
@@ -391,28 +405,23 @@ func (a *ApiHandler) HandleCallbackSelectNewLogDate(data string) {
 		Id:            uuid.NewString(),
 		StartWorkTime: startTime,
 		EndWorkTime:   parsedTime,
-		Message:       a.cachedMessage,
+		Message:       settings.PendingMessage,
+		Project:       settings.PendingProject,
+		Tags:          settings.PendingTags,
+		IssueKey:      utils.FindIssueKey(settings.PendingMessage, settings.IssuePatterns),
 	}
-	err = a.provider.InsertNewLogRecord(parsedTime, newLog)
-	a.cachedMessage = ""
+	err = a.insertLog(userId, parsedTime, newLog)
 
 	if err != nil {
 		logrus.Errorf("Failed to insert new log record: %v", err)
 	}
 
 	if settings.NeedWorkLogTo.Round(time.Second).Compare(parsedTime.Round(time.Second)) <= 0 {
-		settings.CurrentState = constants.UserStateNone
-		err = a.provider.SetUserSettings(settings)
-
-		if err != nil {
-			logrus.Errorf("Failed set user settings: %v", err)
-		}
-
+		a.completeWorkLog(userId)
 		return
 	}
 
-	settings.CurrentState = constants.UserStateSelectNewLogMessage
-	err = a.provider.SetUserSettings(settings)
+	settings, err = a.setUserState(userId, constants.UserStateSelectNewLogMessage)
 
 	if err != nil {
 		logrus.Errorf("Failed to set user settings: %v", err)
@@ -430,15 +439,15 @@ func (a *ApiHandler) HandleCallbackSelectNewLogDate(data string) {
 	}
 }
 
-func (a *ApiHandler) HandleGetLogsCommand() {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleGetLogsCommand(userId int64) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("failed to get user settings: %v", err)
 		return
 	}
 
-	logs, err := a.provider.GetLogRecords(time.Now())
+	logs, err := a.provider.GetLogRecords(userId, time.Now())
 
 	if err != nil {
 		logrus.Errorf("Failed to get logs: %v", err)
@@ -466,15 +475,15 @@ func (a *ApiHandler) HandleGetLogsCommand() {
 	}
 }
 
-func (a *ApiHandler) HandleGetAllLogsCommand() {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleGetAllLogsCommand(userId int64) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("failed to get user settings: %v", err)
 		return
 	}
 
-	availableDates, err := a.provider.GetDatesWithLogs()
+	availableDates, err := a.provider.GetDatesWithLogs(userId)
 
 	if err != nil {
 		logrus.Errorf("Failed to get dates: %v", err)
@@ -493,17 +502,7 @@ func (a *ApiHandler) HandleGetAllLogsCommand() {
 		return
 	}
 
-	var markup []models.MarkupData
-	for _, date := range availableDates {
-		markup = append(markup, models.MarkupData{
-			Key:   date,
-			Value: date,
-		})
-	}
-
-	settings.CurrentState = constants.UserStateSelectLogDate
-
-	err = a.provider.SetUserSettings(settings)
+	settings, err = a.setUserState(userId, constants.UserStateSelectLogDate)
 
 	if err != nil {
 		logrus.Errorf("Failed to set user settings: %v", err)
@@ -511,9 +510,9 @@ func (a *ApiHandler) HandleGetAllLogsCommand() {
 	}
 
 	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
-		ChatId: settings.UserId,
-		Body:   "Выберите дату, за которую вы хотите получить логи",
-		Markup: markup,
+		ChatId:   settings.UserId,
+		Body:     "Выберите дату, за которую вы хотите получить логи, дни с логами отмечены точкой",
+		Calendar: newDatesCalendar(availableDates),
 	})
 
 	if err != nil {
@@ -521,17 +520,15 @@ func (a *ApiHandler) HandleGetAllLogsCommand() {
 	}
 }
 
-func (a *ApiHandler) HandleCallbackWithGetLog(data string) {
-	settings, err := a.provider.GetUserSettings()
+func (a *ApiHandler) HandleCallbackWithGetLog(userId int64, data string) {
+	settings, err := a.provider.GetUserSettings(userId)
 
 	if err != nil {
 		logrus.Errorf("failed to get user settings: %v", err)
 		return
 	}
 
-	settings.CurrentState = constants.UserStateNone
-
-	err = a.provider.SetUserSettings(settings)
+	settings, err = a.setUserState(userId, constants.UserStateNone)
 
 	if err != nil {
 		logrus.Errorf("Failed to set user settings: %v", err)
@@ -545,7 +542,7 @@ func (a *ApiHandler) HandleCallbackWithGetLog(data string) {
 		return
 	}
 
-	logs, err := a.provider.GetLogRecords(parsedDate)
+	logs, err := a.provider.GetLogRecords(userId, parsedDate)
 
 	if err != nil {
 		logrus.Errorf("Failed to get logs: %v", err)
@@ -576,6 +573,22 @@ func (a *ApiHandler) HandleCallbackWithGetLog(data string) {
 	}
 }
 
+// newDatesCalendar opens on the month of the latest date, only the given
+// dates can be picked.
+func newDatesCalendar(dates []string) *models.CalendarDto {
+	calendar := &models.CalendarDto{MarkedDates: dates, OnlyMarked: true}
+
+	for _, v := range dates {
+		date, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+
+		if err == nil && date.After(calendar.Month) {
+			calendar.Month = date
+		}
+	}
+
+	return calendar
+}
+
 func getFirstLog(logs []models.LogsInfoDto) models.LogsInfoDto {
 	firstLogIndex := 0
 
@@ -600,26 +613,120 @@ func getLastLog(logs []models.LogsInfoDto) models.LogsInfoDto {
 	return logs[lastLogIndex]
 }
 
+func isLogDraftState(state constants.UserState) bool {
+	return state == constants.UserStateSelectIssueSuggestion ||
+		state == constants.UserStateSelectProject ||
+		state == constants.UserStateSelectTags ||
+		state == constants.UserStateSelectNewLogDate
+}
+
 func constructLogsTable(logs []models.LogsInfoDto) string {
 	firstLog := getFirstLog(logs)
 	lastLog := getLastLog(logs)
 
 	messageText := fmt.Sprintf("Отчет по времени за период: %s-%s:\n", utils.GetOnlyTime(firstLog.StartWorkTime), utils.GetOnlyTime(lastLog.EndWorkTime))
+	total := time.Duration(0)
 
 	for _, v := range logs {
 		delta := v.EndWorkTime.Sub(v.StartWorkTime)
-		diffStr := ""
+		total += delta
 
-		if int(delta.Hours()) > 0 {
-			diffStr += fmt.Sprintf("%dh", int(delta.Hours()))
+		messageText += fmt.Sprintf("Задача: %s%s, Начало работ: %s, Конец работ: %s, Затрачено времени: %s \n", v.Message, formatLogLabels(v), utils.GetOnlyTime(v.StartWorkTime), utils.GetOnlyTime(v.EndWorkTime), formatDuration(delta))
+	}
+
+	messageText += fmt.Sprintf("Итого: %s", formatDuration(total))
+	messageText += constructTotalsSection("По проектам", aggregateByProject(logs))
+	messageText += constructTotalsSection("По тегам", aggregateByTag(logs))
+	messageText += constructTotalsSection("По ключам задач", aggregateByIssue(logs))
+
+	return messageText
+}
+
+func formatDuration(delta time.Duration) string {
+	diffStr := ""
+
+	if int(delta.Hours()) > 0 {
+		diffStr += fmt.Sprintf("%dh", int(delta.Hours()))
+	}
+
+	if int(delta.Minutes())%60 > 0 {
+		diffStr += fmt.Sprintf(" %dm", int(delta.Minutes())%60)
+	}
+
+	if diffStr == "" {
+		return "0m"
+	}
+
+	return strings.TrimSpace(diffStr)
+}
+
+// completeWorkLog returns the user to the idle state once the requested period
+// is fully logged and delivers the pending daily summary, if any.
+func (a *ApiHandler) completeWorkLog(userId int64) {
+	settings, err := a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if !settings.SummaryPending {
+		return
+	}
+
+	err = a.scheduler.SendDailySummary(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to send daily summary: %v", err)
+	}
+}
+
+func (a *ApiHandler) setUserState(userId int64, state constants.UserState) (*models.UserSettingsDto, error) {
+	return a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = state
+
+		if !isLogDraftState(state) {
+			settings.PendingMessage = ""
+			settings.PendingProject = ""
+			settings.PendingTags = nil
+			settings.PendingHint = ""
 		}
 
-		if int(delta.Minutes()) > 0 {
-			diffStr += fmt.Sprintf(" %dm", int(delta.Minutes()))
+		if !isEditState(state) {
+			settings.EditLogId = ""
+			settings.EditLogDate = ""
+			settings.EditField = ""
 		}
 
-		messageText += fmt.Sprintf("Задача: %s, Начало работ: %s, Конец работ: %s, Затрачено времени: %s \n", v.Message, utils.GetOnlyTime(v.StartWorkTime), utils.GetOnlyTime(v.EndWorkTime), diffStr)
-	}
+		if !isDeleteState(state) {
+			settings.DeleteLogId = ""
+			settings.DeleteLogDate = ""
+		}
 
-	return messageText
+		if state != constants.UserStateSelectReportTo {
+			settings.ReportFrom = ""
+		}
+
+		if state != constants.UserStateConfirmQuickLog {
+			settings.PendingLog = nil
+		}
+
+		return nil
+	})
+}
+
+// sendMessage sends a long body, such as a report over a long period, in
+// several messages.
+func (a *ApiHandler) sendMessage(chatId int64, body string) {
+	for _, v := range utils.SplitMessage(body, maxMessageLength) {
+		err := a.tgClient.SendMessage(&models.SendNotificationRequest{
+			ChatId: chatId,
+			Body:   v,
+		})
+
+		if err != nil {
+			logrus.Errorf("Failed to send message: %v", err)
+			return
+		}
+	}
 }
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxButtonTextLength = 60
+
+func (a *ApiHandler) HandleIssuePatternsCommand(userId int64, args string) {
+	action, pattern, _ := strings.Cut(strings.TrimSpace(args), " ")
+
+	if action == catalogAddArg {
+		_, err := regexp.Compile(strings.TrimSpace(pattern))
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Некорректное регулярное выражение: %v", err))
+			return
+		}
+	}
+
+	a.manageCatalog(userId, args, constants.IssuePatternsCommand, fmt.Sprintf("Шаблоны задач (по умолчанию %s)", utils.DefaultIssueKeyPattern), func(settings *models.UserSettingsDto) *[]string {
+		return &settings.IssuePatterns
+	})
+}
+
+func (a *ApiHandler) HandleCallbackSelectIssueSuggestion(userId int64, data string) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	message := settings.PendingMessage
+
+	if data == constants.CallbackParamUseSuggestion && settings.PendingHint != "" {
+		message = settings.PendingHint
+	}
+
+	a.startLogDraft(userId, message)
+}
+
+// suggestIssueMessage offers the last message used with the issue key found
+// in the typed message. It returns false when there is nothing to suggest.
+func (a *ApiHandler) suggestIssueMessage(userId int64, message string) bool {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return false
+	}
+
+	issueKey := utils.FindIssueKey(message, settings.IssuePatterns)
+
+	if issueKey == "" {
+		return false
+	}
+
+	lastLog, err := a.provider.GetLastLogByIssueKey(userId, issueKey)
+
+	if err != nil {
+		logrus.Errorf("Failed to get last log by issue key: %v", err)
+		return false
+	}
+
+	if lastLog == nil || lastLog.Message == message {
+		return false
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectIssueSuggestion
+		settings.PendingMessage = message
+		settings.PendingHint = lastLog.Message
+		settings.PendingProject = ""
+		settings.PendingTags = nil
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return false
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: userId,
+		Body:   fmt.Sprintf("По задаче %s в прошлый раз вы писали: %s\nИспользовать это сообщение?", issueKey, lastLog.Message),
+		Markup: []models.MarkupData{
+			{
+				Key:   truncateText("Использовать: "+lastLog.Message, maxButtonTextLength),
+				Value: constants.CallbackParamUseSuggestion,
+			},
+			{
+				Key:   truncateText("Оставить: "+message, maxButtonTextLength),
+				Value: constants.CallbackParamKeepMessage,
+			},
+		},
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+
+	return true
+}
+
+func aggregateByIssue(logs []models.LogsInfoDto) []models.ReportTotalDto {
+	return aggregateDurations(logs, func(log models.LogsInfoDto) []string {
+		if log.IssueKey == "" {
+			return nil
+		}
+
+		return []string{log.IssueKey}
+	})
+}
+
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/jira"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/provider"
+	"logs-aggregator-bot/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	jiraSyncAttempts     = 3
+	jiraSyncRetryDelay   = 5 * time.Second
+	jiraMinWorklogLength = time.Minute
+	jiraAuthOffArg       = "off"
+)
+
+type jiraClient interface {
+	AddWorklog(ctx context.Context, credentials jira.Credentials, issueKey string, worklog jira.Worklog) (string, error)
+	UpdateWorklog(ctx context.Context, credentials jira.Credentials, issueKey string, worklogId string, worklog jira.Worklog) error
+	DeleteWorklog(ctx context.Context, credentials jira.Credentials, issueKey string, worklogId string) error
+}
+
+type JiraSyncService struct {
+	provider   provider.StorageProvider
+	client     jiraClient
+	tgClient   tgClient
+	retryDelay time.Duration
+	mu         sync.Mutex
+	// syncing holds the logs being pushed, true means the log changed meanwhile
+	// and has to be pushed once more.
+	syncing map[string]bool
+}
+
+func NewJiraSyncService(provider provider.StorageProvider, client jiraClient, tgCli tgClient) *JiraSyncService {
+	return &JiraSyncService{provider: provider, client: client, tgClient: tgCli, retryDelay: jiraSyncRetryDelay, syncing: map[string]bool{}}
+}
+
+// IsEnabled reports whether the integration is configured, a nil service
+// stands for the disabled one.
+func (j *JiraSyncService) IsEnabled() bool {
+	return j != nil
+}
+
+// Sync pushes the finished log to Jira in the background. Logs without an
+// issue key or without user credentials are skipped, a worklog left from an
+// earlier key is removed.
+func (j *JiraSyncService) Sync(userId int64, log models.LogsInfoDto) {
+	if !j.IsEnabled() || (log.IssueKey == "" && log.JiraWorklogId == "") {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, exist := j.syncing[log.Id]; exist {
+		j.syncing[log.Id] = true
+		return
+	}
+
+	j.syncing[log.Id] = false
+
+	go j.run(userId, log)
+}
+
+// Delete removes the worklogs of the deleted logs from Jira in the background.
+func (j *JiraSyncService) Delete(userId int64, logs []models.LogsInfoDto) {
+	if !j.IsEnabled() {
+		return
+	}
+
+	go func() {
+		for _, v := range logs {
+			if v.JiraWorklogId == "" {
+				continue
+			}
+
+			err := j.delete(userId, v)
+
+			if err != nil {
+				logrus.Errorf("Failed to delete jira worklog of log %s: %v", v.Id, err)
+				j.sendMessage(userId, fmt.Sprintf("Не удалось удалить из Jira запись %s (%s): %v", v.Message, getWorklogIssueKey(v), err))
+			}
+		}
+	}()
+}
+
+func (j *JiraSyncService) run(userId int64, log models.LogsInfoDto) {
+	for {
+		err := j.sync(userId, log)
+
+		if err != nil {
+			logrus.Errorf("Failed to sync log %s with jira: %v", log.Id, err)
+		}
+
+		j.mu.Lock()
+
+		if !j.syncing[log.Id] {
+			delete(j.syncing, log.Id)
+			j.mu.Unlock()
+			return
+		}
+
+		j.syncing[log.Id] = false
+		j.mu.Unlock()
+	}
+}
+
+func (j *JiraSyncService) sync(userId int64, log models.LogsInfoDto) error {
+	settings, err := j.provider.GetUserSettings(userId)
+
+	if err != nil {
+		return err
+	}
+
+	if settings.JiraToken == "" {
+		return nil
+	}
+
+	// The log is read again so that the latest times and worklog id are pushed.
+	stored, err := j.findLog(userId, log)
+
+	if err != nil || stored == nil {
+		return err
+	}
+
+	pushed := stored.IssueKey != "" && stored.EndWorkTime.Sub(stored.StartWorkTime) >= jiraMinWorklogLength
+
+	// The worklog stays on the issue it was added to, it is removed when the
+	// key is cleared or changed and when the log became too short.
+	if stored.JiraWorklogId != "" && (!pushed || getWorklogIssueKey(*stored) != stored.IssueKey) {
+		err = j.delete(userId, *stored)
+
+		if err != nil {
+			return j.fail(userId, stored, err)
+		}
+
+		stored.JiraWorklogId = ""
+		stored.JiraIssueKey = ""
+		stored.SyncStatus = ""
+		stored.SyncError = ""
+
+		err = j.provider.UpdateLogSyncStatus(userId, stored)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if !pushed {
+		return nil
+	}
+
+	stored.SyncStatus = constants.SyncStatusPending
+	err = j.provider.UpdateLogSyncStatus(userId, stored)
+
+	if err != nil {
+		return err
+	}
+
+	added := stored.JiraWorklogId == ""
+	err = j.push(settings, stored)
+
+	if err == nil && added {
+		// The log may have been deleted while its worklog was being added, the
+		// worklog would be left in Jira then.
+		current, err := j.findLog(userId, *stored)
+
+		if err != nil {
+			return err
+		}
+
+		if current == nil {
+			return j.delete(userId, *stored)
+		}
+	}
+
+	if err != nil {
+		return j.fail(userId, stored, err)
+	}
+
+	stored.SyncStatus = constants.SyncStatusSynced
+	stored.SyncError = ""
+
+	return j.provider.UpdateLogSyncStatus(userId, stored)
+}
+
+// fail stores the sync error and tells the user how to retry.
+func (j *JiraSyncService) fail(userId int64, log *models.LogsInfoDto, err error) error {
+	log.SyncStatus = constants.SyncStatusFailed
+	log.SyncError = err.Error()
+
+	j.sendMessage(userId, fmt.Sprintf("Не удалось отправить в Jira запись %s (%s): %v\nПовторить: /%s %s",
+		log.Message, getWorklogIssueKey(*log), err, constants.JiraSyncCommand, utils.GetOnlyDate(log.EndWorkTime)))
+
+	return j.provider.UpdateLogSyncStatus(userId, log)
+}
+
+func (j *JiraSyncService) push(settings *models.UserSettingsDto, log *models.LogsInfoDto) error {
+	credentials := jira.Credentials{Email: settings.JiraEmail, Token: settings.JiraToken}
+	worklog := jira.Worklog{
+		Started:   log.StartWorkTime,
+		TimeSpent: log.EndWorkTime.Sub(log.StartWorkTime),
+		Comment:   log.Message,
+	}
+
+	if log.JiraWorklogId != "" {
+		err := j.retry(func() error {
+			return j.client.UpdateWorklog(context.TODO(), credentials, log.IssueKey, log.JiraWorklogId, worklog)
+		})
+
+		// A missing worklog was removed with its trashed day, the restored log
+		// gets a new one.
+		if !isJiraNotFound(err) {
+			return err
+		}
+
+		log.JiraWorklogId = ""
+	}
+
+	return j.retry(func() error {
+		worklogId, err := j.client.AddWorklog(context.TODO(), credentials, log.IssueKey, worklog)
+
+		if err == nil {
+			log.JiraWorklogId = worklogId
+			log.JiraIssueKey = log.IssueKey
+		}
+
+		return err
+	})
+}
+
+func (j *JiraSyncService) delete(userId int64, log models.LogsInfoDto) error {
+	settings, err := j.provider.GetUserSettings(userId)
+
+	if err != nil {
+		return err
+	}
+
+	if settings.JiraToken == "" {
+		return nil
+	}
+
+	credentials := jira.Credentials{Email: settings.JiraEmail, Token: settings.JiraToken}
+
+	err = j.retry(func() error {
+		return j.client.DeleteWorklog(context.TODO(), credentials, getWorklogIssueKey(log), log.JiraWorklogId)
+	})
+
+	if isJiraNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// retry repeats the call while Jira answers with a retryable error.
+func (j *JiraSyncService) retry(call func() error) error {
+	var err error
+
+	for attempt := 1; attempt <= jiraSyncAttempts; attempt++ {
+		err = call()
+
+		var jiraErr *jira.Error
+
+		if err == nil || (errors.As(err, &jiraErr) && !jiraErr.Retryable()) {
+			return err
+		}
+
+		if attempt < jiraSyncAttempts {
+			time.Sleep(j.retryDelay * time.Duration(attempt))
+		}
+	}
+
+	return err
+}
+
+// getWorklogIssueKey returns the issue holding the worklog, logs synced before
+// the issue was stored along with the worklog keep it in IssueKey.
+func getWorklogIssueKey(log models.LogsInfoDto) string {
+	if log.JiraIssueKey == "" {
+		return log.IssueKey
+	}
+
+	return log.JiraIssueKey
+}
+
+func isJiraNotFound(err error) bool {
+	var jiraErr *jira.Error
+	return errors.As(err, &jiraErr) && jiraErr.NotFound()
+}
+
+// findLog looks the log up in the days of its end and start, the log is
+// stored under the former but may be moved by an update.
+func (j *JiraSyncService) findLog(userId int64, log models.LogsInfoDto) (*models.LogsInfoDto, error) {
+	for _, date := range []time.Time{log.EndWorkTime, log.StartWorkTime} {
+		logs, err := j.provider.GetLogRecords(userId, date)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range logs {
+			if v.Id == log.Id {
+				return &v, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (j *JiraSyncService) sendMessage(chatId int64, body string) {
+	err := j.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: chatId,
+		Body:   body,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) HandleJiraAuthCommand(userId int64, args string) {
+	if !a.jira.IsEnabled() {
+		a.sendMessage(userId, "Интеграция с Jira не настроена")
+		return
+	}
+
+	fields := strings.Fields(args)
+
+	if len(fields) == 0 || len(fields) > 2 {
+		a.sendMessage(userId, fmt.Sprintf("Использование: /%s <email> <api token>, /%s <personal access token> или /%s %s",
+			constants.JiraAuthCommand, constants.JiraAuthCommand, constants.JiraAuthCommand, jiraAuthOffArg))
+		return
+	}
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.JiraEmail = ""
+		settings.JiraToken = ""
+
+		switch {
+		case len(fields) == 2:
+			settings.JiraEmail = fields[0]
+			settings.JiraToken = fields[1]
+		case fields[0] != jiraAuthOffArg:
+			settings.JiraToken = fields[0]
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if fields[0] == jiraAuthOffArg {
+		a.sendMessage(userId, "Синхронизация с Jira отключена")
+		return
+	}
+
+	a.sendMessage(userId, "Доступ к Jira сохранен. Записи с ключом задачи будут отправляться в Jira")
+}
+
+func (a *ApiHandler) HandleJiraSyncCommand(userId int64, args string) {
+	if !a.jira.IsEnabled() {
+		a.sendMessage(userId, "Интеграция с Jira не настроена")
+		return
+	}
+
+	date := time.Now()
+
+	if strings.TrimSpace(args) != "" {
+		parsedDate, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(args), time.Local)
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Использование: /%s [ГГГГ-ММ-ДД]", constants.JiraSyncCommand))
+			return
+		}
+
+		date = parsedDate
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	count := 0
+
+	for _, v := range logs {
+		if (v.IssueKey == "" && v.JiraWorklogId == "") || v.SyncStatus == constants.SyncStatusSynced {
+			continue
+		}
+
+		a.jira.Sync(userId, v)
+		count++
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Отправка в Jira запущена для записей: %d", count))
+}
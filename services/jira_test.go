@@ -0,0 +1,345 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/jira"
+	"logs-aggregator-bot/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const jiraTestUserId = 1
+
+// jiraStandIn answers the worklog requests with the queued statuses, a success
+// once the queue is empty.
+type jiraStandIn struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []string
+}
+
+func (s *jiraStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if len(s.statuses) > 0 {
+		status := s.statuses[0]
+		s.statuses = s.statuses[1:]
+
+		if status >= http.StatusBadRequest {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, fmt.Sprintf(`{"id":"%d"}`, len(s.requests)))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newJiraTestService(t *testing.T, standIn *jiraStandIn, logs ...models.LogsInfoDto) (*JiraSyncService, *fakeTgClient) {
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	storage := newFakeStorage(models.UserSettingsDto{UserId: jiraTestUserId, JiraToken: "pat"})
+	tgCli := &fakeTgClient{}
+
+	for _, v := range logs {
+		err := storage.InsertNewLogRecord(jiraTestUserId, v.EndWorkTime, &v)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewJiraSyncService(storage, jira.NewClient(server.URL, nil), tgCli)
+	service.retryDelay = 0
+
+	return service, tgCli
+}
+
+func newJiraTestLog(worklogId string) models.LogsInfoDto {
+	start := time.Date(2024, 5, 13, 10, 0, 0, 0, time.Local)
+
+	return models.LogsInfoDto{
+		Id:            "log",
+		StartWorkTime: start,
+		EndWorkTime:   start.Add(time.Hour),
+		Message:       "PROJ-1 review",
+		IssueKey:      "PROJ-1",
+		JiraWorklogId: worklogId,
+	}
+}
+
+func TestJiraSync(t *testing.T) {
+	tests := []struct {
+		name      string
+		worklogId string
+		statuses  []int
+		requests  []string
+		status    constants.SyncStatus
+		wantId    string
+		messages  int
+	}{
+		{
+			name:     "add",
+			requests: []string{"POST /rest/api/2/issue/PROJ-1/worklog"},
+			status:   constants.SyncStatusSynced,
+			wantId:   "1",
+		},
+		{
+			name:      "update",
+			worklogId: "7",
+			requests:  []string{"PUT /rest/api/2/issue/PROJ-1/worklog/7"},
+			status:    constants.SyncStatusSynced,
+			wantId:    "7",
+		},
+		{
+			name:     "retry until added",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			requests: []string{
+				"POST /rest/api/2/issue/PROJ-1/worklog",
+				"POST /rest/api/2/issue/PROJ-1/worklog",
+				"POST /rest/api/2/issue/PROJ-1/worklog",
+			},
+			status: constants.SyncStatusSynced,
+			wantId: "3",
+		},
+		{
+			name:      "retries exhausted",
+			worklogId: "7",
+			statuses:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			requests: []string{
+				"PUT /rest/api/2/issue/PROJ-1/worklog/7",
+				"PUT /rest/api/2/issue/PROJ-1/worklog/7",
+				"PUT /rest/api/2/issue/PROJ-1/worklog/7",
+			},
+			status:   constants.SyncStatusFailed,
+			wantId:   "7",
+			messages: 1,
+		},
+		{
+			name:     "not retryable",
+			statuses: []int{http.StatusBadRequest},
+			requests: []string{"POST /rest/api/2/issue/PROJ-1/worklog"},
+			status:   constants.SyncStatusFailed,
+			messages: 1,
+		},
+		{
+			name:      "missing worklog is added again",
+			worklogId: "7",
+			statuses:  []int{http.StatusNotFound},
+			requests: []string{
+				"PUT /rest/api/2/issue/PROJ-1/worklog/7",
+				"POST /rest/api/2/issue/PROJ-1/worklog",
+			},
+			status: constants.SyncStatusSynced,
+			wantId: "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &jiraStandIn{statuses: tt.statuses}
+			log := newJiraTestLog(tt.worklogId)
+			service, tgCli := newJiraTestService(t, standIn, log)
+
+			err := service.sync(jiraTestUserId, log)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(standIn.requests) != fmt.Sprint(tt.requests) {
+				t.Errorf("got requests %v, want %v", standIn.requests, tt.requests)
+			}
+
+			stored, err := service.findLog(jiraTestUserId, log)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.SyncStatus != tt.status || stored.JiraWorklogId != tt.wantId {
+				t.Errorf("got status %q worklog %q, want %q %q", stored.SyncStatus, stored.JiraWorklogId, tt.status, tt.wantId)
+			}
+
+			if (tt.status == constants.SyncStatusFailed) != (stored.SyncError != "") {
+				t.Errorf("got sync error %q", stored.SyncError)
+			}
+
+			if len(tgCli.messages) != tt.messages {
+				t.Errorf("got %d messages, want %d", len(tgCli.messages), tt.messages)
+			}
+		})
+	}
+}
+
+func TestJiraSyncSkipsShortLogs(t *testing.T) {
+	standIn := &jiraStandIn{}
+	log := newJiraTestLog("")
+	log.EndWorkTime = log.StartWorkTime.Add(30 * time.Second)
+	service, _ := newJiraTestService(t, standIn, log)
+
+	err := service.sync(jiraTestUserId, log)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(standIn.requests) != 0 {
+		t.Errorf("got requests %v, want none", standIn.requests)
+	}
+}
+
+func TestJiraSyncMovesWorklog(t *testing.T) {
+	tests := []struct {
+		name     string
+		issueKey string
+		length   time.Duration
+		statuses []int
+		requests []string
+		status   constants.SyncStatus
+		wantId   string
+		wantKey  string
+	}{
+		{
+			name:     "key removed",
+			length:   time.Hour,
+			requests: []string{"DELETE /rest/api/2/issue/PROJ-1/worklog/7"},
+		},
+		{
+			name:     "too short",
+			issueKey: "PROJ-1",
+			length:   30 * time.Second,
+			requests: []string{"DELETE /rest/api/2/issue/PROJ-1/worklog/7"},
+		},
+		{
+			name:     "key changed",
+			issueKey: "PROJ-2",
+			length:   time.Hour,
+			requests: []string{
+				"DELETE /rest/api/2/issue/PROJ-1/worklog/7",
+				"POST /rest/api/2/issue/PROJ-2/worklog",
+			},
+			status:  constants.SyncStatusSynced,
+			wantId:  "2",
+			wantKey: "PROJ-2",
+		},
+		{
+			name:     "delete failed",
+			issueKey: "PROJ-2",
+			length:   time.Hour,
+			statuses: []int{http.StatusForbidden},
+			requests: []string{"DELETE /rest/api/2/issue/PROJ-1/worklog/7"},
+			status:   constants.SyncStatusFailed,
+			wantId:   "7",
+			wantKey:  "PROJ-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &jiraStandIn{statuses: tt.statuses}
+			log := newJiraTestLog("7")
+			log.JiraIssueKey = "PROJ-1"
+			log.IssueKey = tt.issueKey
+			log.EndWorkTime = log.StartWorkTime.Add(tt.length)
+			service, _ := newJiraTestService(t, standIn, log)
+
+			err := service.sync(jiraTestUserId, log)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(standIn.requests) != fmt.Sprint(tt.requests) {
+				t.Errorf("got requests %v, want %v", standIn.requests, tt.requests)
+			}
+
+			stored, err := service.findLog(jiraTestUserId, log)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.SyncStatus != tt.status || stored.JiraWorklogId != tt.wantId || stored.JiraIssueKey != tt.wantKey {
+				t.Errorf("got status %q worklog %q on %q, want %q %q on %q",
+					stored.SyncStatus, stored.JiraWorklogId, stored.JiraIssueKey, tt.status, tt.wantId, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestJiraDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		requests int
+	}{
+		{name: "deleted", requests: 1},
+		{name: "already missing", statuses: []int{http.StatusNotFound}, requests: 1},
+		{name: "retried", statuses: []int{http.StatusServiceUnavailable}, requests: 2},
+		{name: "forbidden", statuses: []int{http.StatusForbidden}, wantErr: true, requests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &jiraStandIn{statuses: tt.statuses}
+			service, _ := newJiraTestService(t, standIn)
+
+			err := service.delete(jiraTestUserId, newJiraTestLog("7"))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if len(standIn.requests) != tt.requests || standIn.requests[0] != "DELETE /rest/api/2/issue/PROJ-1/worklog/7" {
+				t.Errorf("got requests %v", standIn.requests)
+			}
+		})
+	}
+}
+
+func TestJiraSyncDeletesWorklogOfDeletedLog(t *testing.T) {
+	log := newJiraTestLog("")
+	storage := newFakeStorage(models.UserSettingsDto{UserId: jiraTestUserId, JiraToken: "pat"})
+
+	// The log is deleted while Jira is adding its worklog.
+	standIn := &jiraStandIn{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_ = storage.DeleteLogRecord(jiraTestUserId, "2024-05-13", log.Id)
+		}
+
+		standIn.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	_ = storage.InsertNewLogRecord(jiraTestUserId, log.EndWorkTime, &log)
+	service := NewJiraSyncService(storage, jira.NewClient(server.URL, nil), &fakeTgClient{})
+
+	err := service.sync(jiraTestUserId, log)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[POST /rest/api/2/issue/PROJ-1/worklog DELETE /rest/api/2/issue/PROJ-1/worklog/1]"
+
+	if fmt.Sprint(standIn.requests) != want {
+		t.Errorf("got requests %v, want %s", standIn.requests, want)
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrLogNotFound = errors.New("log not found")
+	ErrInvalidLog  = errors.New("invalid log")
+)
+
+// CreateLog stores a log made outside of the reminder flow. The log is kept
+// under the day of its end, like the logs created from Telegram, and must not
+// overlap other logs.
+func (a *ApiHandler) CreateLog(userId int64, log *models.LogsInfoDto) error {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		return err
+	}
+
+	err = validateLog(log)
+
+	if err != nil {
+		return err
+	}
+
+	log.Id = uuid.NewString()
+	dates := []time.Time{log.EndWorkTime}
+
+	// A log crossing midnight may overlap the logs of the previous day too.
+	if utils.GetOnlyDate(log.StartWorkTime) != utils.GetOnlyDate(log.EndWorkTime) {
+		dates = append(dates, log.StartWorkTime)
+	}
+
+	for _, date := range dates {
+		logs, err := a.provider.GetLogRecords(userId, date)
+
+		if err != nil {
+			return err
+		}
+
+		if overlap := findOverlap(logs, *log); overlap != nil {
+			return overlapError(overlap)
+		}
+	}
+
+	log.IssueKey = utils.FindIssueKey(log.Message, settings.IssuePatterns)
+	log.JiraWorklogId = ""
+	log.JiraIssueKey = ""
+	log.SyncStatus = ""
+	log.SyncError = ""
+
+	return a.insertLog(userId, log.EndWorkTime, log)
+}
+
+// UpdateLog replaces the editable fields of the log stored under the date.
+// The log has to stay within that date and its new times must not overlap
+// other logs.
+func (a *ApiHandler) UpdateLog(userId int64, date time.Time, log *models.LogsInfoDto) error {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		return err
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		return err
+	}
+
+	stored := findLog(logs, log.Id)
+
+	if stored == nil {
+		return ErrLogNotFound
+	}
+
+	err = validateLog(log)
+
+	if err != nil {
+		return err
+	}
+
+	if utils.GetOnlyDate(log.EndWorkTime) != utils.GetOnlyDate(date) {
+		return fmt.Errorf("%w: end must stay within %s", ErrInvalidLog, utils.GetOnlyDate(date))
+	}
+
+	timesChanged := !log.StartWorkTime.Equal(stored.StartWorkTime) || !log.EndWorkTime.Equal(stored.EndWorkTime)
+
+	if overlap := findOverlap(logs, *log); timesChanged && overlap != nil {
+		return overlapError(overlap)
+	}
+
+	stored.StartWorkTime = log.StartWorkTime
+	stored.EndWorkTime = log.EndWorkTime
+	stored.Message = log.Message
+	stored.Project = log.Project
+	stored.Tags = log.Tags
+	stored.IssueKey = utils.FindIssueKey(log.Message, settings.IssuePatterns)
+
+	err = a.updateLog(userId, stored)
+
+	if err != nil {
+		return err
+	}
+
+	*log = *stored
+	return nil
+}
+
+// TrashLogsByDate moves the day to the trash, where it is kept for
+// trashRetention before being purged.
+// The Jira worklogs of the day are deleted, a restore adds them again.
+func (a *ApiHandler) TrashLogsByDate(userId int64, date string) error {
+	parsedDate, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+
+	if err != nil {
+		return err
+	}
+
+	logs, err := a.provider.GetLogRecords(userId, parsedDate)
+
+	if err != nil {
+		return err
+	}
+
+	err = a.provider.TrashLogsByDate(userId, date, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	a.jira.Delete(userId, logs)
+	a.webhooks.Publish(models.WebhookEventDto{Type: constants.WebhookEventLogDeleted, UserId: userId, Date: date})
+	return nil
+}
+
+// RestoreLogsByDate returns the day from the trash, the restored logs are
+// announced as created again.
+func (a *ApiHandler) RestoreLogsByDate(userId int64, date string) ([]models.LogsInfoDto, error) {
+	logs, err := a.provider.RestoreLogsByDate(userId, date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range logs {
+		a.jira.Sync(userId, v)
+		a.webhooks.Publish(models.WebhookEventDto{Type: constants.WebhookEventLogCreated, UserId: userId, Log: &v})
+	}
+
+	return logs, nil
+}
+
+// DeleteLog removes a single log stored under the date and returns it.
+func (a *ApiHandler) DeleteLog(userId int64, date time.Time, logId string) (*models.LogsInfoDto, error) {
+	logs, err := a.provider.GetLogRecords(userId, date)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log := findLog(logs, logId)
+
+	if log == nil {
+		return nil, ErrLogNotFound
+	}
+
+	err = a.provider.DeleteLogRecord(userId, utils.GetOnlyDate(date), logId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.jira.Delete(userId, []models.LogsInfoDto{*log})
+	a.webhooks.Publish(models.WebhookEventDto{Type: constants.WebhookEventLogDeleted, UserId: userId, Date: utils.GetOnlyDate(date), Log: log})
+	return log, nil
+}
+
+func findLog(logs []models.LogsInfoDto, logId string) *models.LogsInfoDto {
+	for _, v := range logs {
+		if v.Id == logId {
+			return &v
+		}
+	}
+
+	return nil
+}
+
+// findOverlap returns the first log other than the given one sharing some time
+// with it.
+func findOverlap(logs []models.LogsInfoDto, log models.LogsInfoDto) *models.LogsInfoDto {
+	for _, v := range logs {
+		if v.Id != log.Id && log.StartWorkTime.Before(v.EndWorkTime) && v.StartWorkTime.Before(log.EndWorkTime) {
+			return &v
+		}
+	}
+
+	return nil
+}
+
+func overlapError(overlap *models.LogsInfoDto) error {
+	return fmt.Errorf("%w: overlaps with %s-%s %s", ErrInvalidLog, utils.GetOnlyTime(overlap.StartWorkTime), utils.GetOnlyTime(overlap.EndWorkTime), overlap.Message)
+}
+
+// getNeighbourBounds returns the end of the log before and the start of the
+// log after the given one, zero when there is no such log.
+func getNeighbourBounds(logs []models.LogsInfoDto, log models.LogsInfoDto) (time.Time, time.Time) {
+	var prevEnd, nextStart time.Time
+
+	for _, v := range logs {
+		if v.Id == log.Id {
+			continue
+		}
+
+		if !v.StartWorkTime.After(log.StartWorkTime) && v.EndWorkTime.After(prevEnd) {
+			prevEnd = v.EndWorkTime
+		}
+
+		if v.StartWorkTime.After(log.StartWorkTime) && (nextStart.IsZero() || v.StartWorkTime.Before(nextStart)) {
+			nextStart = v.StartWorkTime
+		}
+	}
+
+	return prevEnd, nextStart
+}
+
+// insertLog stores the log and notifies the integrations about it.
+func (a *ApiHandler) insertLog(userId int64, date time.Time, log *models.LogsInfoDto) error {
+	err := a.provider.InsertNewLogRecord(userId, date, log)
+
+	if err != nil {
+		return err
+	}
+
+	a.jira.Sync(userId, *log)
+	a.webhooks.Publish(models.WebhookEventDto{Type: constants.WebhookEventLogCreated, UserId: userId, Log: log})
+	return nil
+}
+
+func (a *ApiHandler) updateLog(userId int64, log *models.LogsInfoDto) error {
+	err := a.provider.UpdateLogRecord(userId, log)
+
+	if err != nil {
+		return err
+	}
+
+	a.jira.Sync(userId, *log)
+	a.webhooks.Publish(models.WebhookEventDto{Type: constants.WebhookEventLogUpdated, UserId: userId, Log: log})
+	return nil
+}
+
+func validateLog(log *models.LogsInfoDto) error {
+	log.Message = strings.TrimSpace(log.Message)
+
+	if log.Message == "" {
+		return fmt.Errorf("%w: message is empty", ErrInvalidLog)
+	}
+
+	if log.StartWorkTime.IsZero() || !log.StartWorkTime.Before(log.EndWorkTime) {
+		return fmt.Errorf("%w: start must be before end", ErrInvalidLog)
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	catalogAddArg    = "add"
+	catalogRemoveArg = "remove"
+	noProjectTitle   = "Без проекта"
+)
+
+var errUnknownCatalogItem = errors.New("unknown catalog item")
+
+func (a *ApiHandler) HandleProjectsCommand(userId int64, args string) {
+	a.manageCatalog(userId, args, constants.ProjectsCommand, "Проекты", func(settings *models.UserSettingsDto) *[]string {
+		return &settings.Projects
+	})
+}
+
+func (a *ApiHandler) HandleTagsCommand(userId int64, args string) {
+	a.manageCatalog(userId, args, constants.TagsCommand, "Теги", func(settings *models.UserSettingsDto) *[]string {
+		return &settings.Tags
+	})
+}
+
+func (a *ApiHandler) HandleCallbackSelectProject(userId int64, data string) {
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.PendingProject = ""
+
+		if data != constants.CallbackParamNoProject {
+			project, ok := findCatalogItem(settings.Projects, strings.TrimPrefix(data, constants.CallbackPrefixProject))
+
+			if !ok {
+				return fmt.Errorf("%w: project %q", errUnknownCatalogItem, data)
+			}
+
+			settings.PendingProject = project
+		}
+
+		settings.CurrentState = constants.UserStateSelectTags
+
+		if len(settings.Tags) == 0 {
+			settings.CurrentState = constants.UserStateSelectNewLogDate
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errUnknownCatalogItem) {
+		a.sendMessage(userId, "Список проектов изменился, выберите проект заново")
+		a.resendProjectSelection(userId)
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to select project: %v", err)
+		return
+	}
+
+	if settings.CurrentState == constants.UserStateSelectTags {
+		a.sendTagsSelection(settings)
+		return
+	}
+
+	a.sendNewLogTimeSelection(settings)
+}
+
+// HandleCallbackSelectTags toggles a tag of the log draft and returns the text
+// for the callback answer.
+func (a *ApiHandler) HandleCallbackSelectTags(userId int64, data string) string {
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		if data == constants.CallbackParamTagsDone {
+			settings.CurrentState = constants.UserStateSelectNewLogDate
+			return nil
+		}
+
+		tag, ok := findCatalogItem(settings.Tags, strings.TrimPrefix(data, constants.CallbackPrefixTag))
+
+		if !ok {
+			return fmt.Errorf("%w: tag %q", errUnknownCatalogItem, data)
+		}
+
+		if i := slices.Index(settings.PendingTags, tag); i >= 0 {
+			settings.PendingTags = slices.Delete(settings.PendingTags, i, i+1)
+		} else {
+			settings.PendingTags = append(settings.PendingTags, tag)
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errUnknownCatalogItem) {
+		return "Тег удален из списка"
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to select tag: %v", err)
+		return "Не удалось выбрать тег"
+	}
+
+	if settings.CurrentState == constants.UserStateSelectNewLogDate {
+		a.sendNewLogTimeSelection(settings)
+		return "Запрос обработан успешно"
+	}
+
+	if len(settings.PendingTags) == 0 {
+		return "Теги не выбраны"
+	}
+
+	return "Выбраны теги: " + strings.Join(settings.PendingTags, ", ")
+}
+
+func (a *ApiHandler) sendProjectSelection(settings *models.UserSettingsDto) {
+	var markup []models.MarkupData
+
+	for _, v := range settings.Projects {
+		markup = append(markup, models.MarkupData{
+			Key:   v,
+			Value: constants.CallbackPrefixProject + catalogItemId(v),
+		})
+	}
+
+	err := a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:   settings.UserId,
+		Body:     fmt.Sprintf("Выберите проект для задачи %s", settings.PendingMessage),
+		Markup:   markup,
+		Controls: []models.MarkupData{{Key: noProjectTitle, Value: constants.CallbackParamNoProject}},
+		Columns:  2,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) sendTagsSelection(settings *models.UserSettingsDto) {
+	var markup []models.MarkupData
+
+	for _, v := range settings.Tags {
+		markup = append(markup, models.MarkupData{
+			Key:   "#" + v,
+			Value: constants.CallbackPrefixTag + catalogItemId(v),
+		})
+	}
+
+	err := a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:        settings.UserId,
+		Body:          fmt.Sprintf("Отметьте теги для задачи %s и нажмите \"Готово\"", settings.PendingMessage),
+		Markup:        markup,
+		Controls:      []models.MarkupData{{Key: "Готово", Value: constants.CallbackParamTagsDone}},
+		Columns:       2,
+		IsMultiSelect: true,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) manageCatalog(userId int64, args string, command constants.Commands, title string, catalog func(settings *models.UserSettingsDto) *[]string) {
+	action, name, _ := strings.Cut(strings.TrimSpace(args), " ")
+	name = strings.TrimSpace(name)
+
+	if action != "" && (name == "" || (action != catalogAddArg && action != catalogRemoveArg)) {
+		a.sendMessage(userId, fmt.Sprintf("Использование: /%s, /%s %s <название>, /%s %s <название>", command, command, catalogAddArg, command, catalogRemoveArg))
+		return
+	}
+
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		items := catalog(settings)
+		index := slices.Index(*items, name)
+
+		switch {
+		case action == catalogAddArg && index < 0:
+			*items = append(*items, name)
+		case action == catalogRemoveArg && index >= 0:
+			*items = slices.Delete(*items, index, index+1)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to update user settings: %v", err)
+		return
+	}
+
+	items := *catalog(settings)
+
+	if len(items) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("%s: список пуст. Добавить: /%s %s <название>", title, command, catalogAddArg))
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("%s: %s", title, strings.Join(items, ", ")))
+}
+
+func (a *ApiHandler) resendProjectSelection(userId int64) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	a.sendProjectSelection(settings)
+}
+
+// catalogItemId identifies a project or a tag in the callback data, unlike the
+// list index it stays the same when the list changes, and unlike the name it
+// always fits into the 64 bytes of the data.
+func catalogItemId(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:8])
+}
+
+func findCatalogItem(items []string, id string) (string, bool) {
+	for _, v := range items {
+		if catalogItemId(v) == id {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+func formatLogLabels(log models.LogsInfoDto) string {
+	var labels []string
+
+	if log.Project != "" {
+		labels = append(labels, "проект: "+log.Project)
+	}
+
+	for _, v := range log.Tags {
+		labels = append(labels, "#"+v)
+	}
+
+	if len(labels) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" [%s]", strings.Join(labels, ", "))
+}
+
+func aggregateByProject(logs []models.LogsInfoDto) []models.ReportTotalDto {
+	return aggregateDurations(logs, func(log models.LogsInfoDto) []string {
+		if log.Project == "" {
+			return nil
+		}
+
+		return []string{log.Project}
+	})
+}
+
+func aggregateByTag(logs []models.LogsInfoDto) []models.ReportTotalDto {
+	return aggregateDurations(logs, func(log models.LogsInfoDto) []string {
+		return log.Tags
+	})
+}
+
+func aggregateDurations(logs []models.LogsInfoDto, keys func(log models.LogsInfoDto) []string) []models.ReportTotalDto {
+	totals := map[string]time.Duration{}
+
+	for _, v := range logs {
+		for _, key := range keys(v) {
+			totals[key] += v.EndWorkTime.Sub(v.StartWorkTime)
+		}
+	}
+
+	result := make([]models.ReportTotalDto, 0, len(totals))
+
+	for name, duration := range totals {
+		result = append(result, models.ReportTotalDto{Name: name, Duration: duration})
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		if result[i].Duration == result[k].Duration {
+			return result[i].Name < result[k].Name
+		}
+
+		return result[i].Duration > result[k].Duration
+	})
+
+	return result
+}
+
+func constructTotalsSection(title string, totals []models.ReportTotalDto) string {
+	if len(totals) == 0 {
+		return ""
+	}
+
+	messageText := fmt.Sprintf("\n%s:\n", title)
+
+	for _, v := range totals {
+		messageText += fmt.Sprintf("%s: %s\n", v.Name, formatDuration(v.Duration))
+	}
+
+	return strings.TrimRight(messageText, "\n")
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxQuickLogDuration = 24 * time.Hour
+
+var (
+	quickLogRangePattern = regexp.MustCompile(`^(\d{1,2}:\d{2})\s*[-–—]\s*(\d{1,2}:\d{2})\s+(.+)$`)
+	quickLogSincePattern = regexp.MustCompile(`(?i)^(?:since|с)\s+(\d{1,2}:\d{2})\s+(.+)$`)
+	quickLogUnitReplacer = strings.NewReplacer("мин", "m", "ч", "h", "м", "m", ",", ".")
+)
+
+const quickLogExamples = "Примеры:\n10:00-11:30 code review\n1h30m PROJ-12 refactor\nsince 14:00 meeting"
+
+// HandleQuickLogMessage turns a message sent outside of any flow into a log
+// and asks to confirm it before saving.
+func (a *ApiHandler) HandleQuickLogMessage(userId int64, text string) {
+	now := time.Now()
+	log, ok := parseQuickLog(text, now)
+
+	if !ok {
+		a.sendMessage(userId, "Не удалось распознать запись. "+quickLogExamples)
+		return
+	}
+
+	err := validateLog(&log)
+
+	if err == nil && log.EndWorkTime.After(now.Add(time.Minute)) {
+		err = errors.New("конец записи еще не наступил")
+	}
+
+	if err == nil && log.EndWorkTime.Sub(log.StartWorkTime) > maxQuickLogDuration {
+		err = fmt.Errorf("запись длиннее %s", formatDuration(maxQuickLogDuration))
+	}
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Не удалось добавить запись: %v", err))
+		return
+	}
+
+	for _, date := range []time.Time{log.EndWorkTime, log.StartWorkTime} {
+		logs, err := a.provider.GetLogRecords(userId, date)
+
+		if err != nil {
+			logrus.Errorf("Failed to get logs: %v", err)
+			return
+		}
+
+		if overlap := findOverlap(logs, log); overlap != nil {
+			a.sendMessage(userId, fmt.Sprintf("Запись пересекается с %s %s, укажите другое время. %s",
+				formatLogPeriod(*overlap), overlap.Message, quickLogExamples))
+			return
+		}
+	}
+
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateConfirmQuickLog
+		settings.PendingLog = &log
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	preview := fmt.Sprintf("Добавить запись?\n%s %s (%s)\n%s",
+		utils.GetOnlyDate(log.EndWorkTime), formatLogPeriod(log), formatDuration(log.EndWorkTime.Sub(log.StartWorkTime)), log.Message)
+
+	if issueKey := utils.FindIssueKey(log.Message, settings.IssuePatterns); issueKey != "" {
+		preview += "\nКлюч задачи: " + issueKey
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: userId,
+		Body:   preview,
+		Markup: []models.MarkupData{
+			{Key: "Сохранить", Value: constants.CallbackParamConfirm},
+			{Key: "Отмена", Value: constants.CallbackParamCancel},
+		},
+		Columns: 2,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) HandleCallbackConfirmQuickLog(userId int64, data string) {
+	var log *models.LogsInfoDto
+
+	_, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		log = settings.PendingLog
+		settings.CurrentState = constants.UserStateNone
+		settings.PendingLog = nil
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if data != constants.CallbackParamConfirm || log == nil {
+		a.sendMessage(userId, "Запись не добавлена")
+		return
+	}
+
+	err = a.CreateLog(userId, log)
+
+	if errors.Is(err, ErrInvalidLog) {
+		a.sendMessage(userId, fmt.Sprintf("Не удалось добавить запись: %v", err))
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to create log: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Запись добавлена: %s %s %s", utils.GetOnlyDate(log.EndWorkTime), formatLogPeriod(*log), log.Message))
+}
+
+// parseQuickLog reads "HH:MM-HH:MM message", "since HH:MM message" and
+// "<duration> message", the latter two ending now. A range ending before its
+// start is taken as crossing midnight.
+func parseQuickLog(text string, now time.Time) (models.LogsInfoDto, bool) {
+	text = strings.TrimSpace(text)
+	today := utils.GetStartOfDay(now)
+
+	if match := quickLogRangePattern.FindStringSubmatch(text); match != nil {
+		from, err := utils.ParseDayTime(match[1])
+
+		if err != nil {
+			return models.LogsInfoDto{}, false
+		}
+
+		to, err := utils.ParseDayTime(match[2])
+
+		if err != nil {
+			return models.LogsInfoDto{}, false
+		}
+
+		start := today.Add(from)
+
+		if to < from {
+			start = start.AddDate(0, 0, -1)
+		}
+
+		return models.LogsInfoDto{StartWorkTime: start, EndWorkTime: today.Add(to), Message: match[3]}, true
+	}
+
+	if match := quickLogSincePattern.FindStringSubmatch(text); match != nil {
+		from, err := utils.ParseDayTime(match[1])
+
+		if err != nil {
+			return models.LogsInfoDto{}, false
+		}
+
+		return models.LogsInfoDto{StartWorkTime: today.Add(from), EndWorkTime: now, Message: match[2]}, true
+	}
+
+	duration, message, found := strings.Cut(text, " ")
+
+	if !found {
+		return models.LogsInfoDto{}, false
+	}
+
+	delta, err := time.ParseDuration(quickLogUnitReplacer.Replace(strings.ToLower(duration)))
+
+	if err != nil || delta <= 0 {
+		return models.LogsInfoDto{}, false
+	}
+
+	return models.LogsInfoDto{StartWorkTime: now.Add(-delta), EndWorkTime: now, Message: message}, true
+}
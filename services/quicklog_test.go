@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuickLog(t *testing.T) {
+	now := time.Date(2024, 5, 13, 15, 0, 0, 0, time.Local)
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		text    string
+		ok      bool
+		start   time.Time
+		end     time.Time
+		message string
+	}{
+		{text: "10:00-11:30 code review", ok: true, start: at(13, 10, 0), end: at(13, 11, 30), message: "code review"},
+		{text: " 9:15 — 10:00  standup ", ok: true, start: at(13, 9, 15), end: at(13, 10, 0), message: "standup"},
+		{text: "23:00-01:00 deploy", ok: true, start: at(12, 23, 0), end: at(13, 1, 0), message: "deploy"},
+		{text: "1h30m PROJ-12 refactor", ok: true, start: at(13, 13, 30), end: now, message: "PROJ-12 refactor"},
+		{text: "1ч30мин отчет", ok: true, start: at(13, 13, 30), end: now, message: "отчет"},
+		{text: "45м созвон", ok: true, start: at(13, 14, 15), end: now, message: "созвон"},
+		{text: "1,5h fix", ok: true, start: at(13, 13, 30), end: now, message: "fix"},
+		{text: "since 14:00 meeting", ok: true, start: at(13, 14, 0), end: now, message: "meeting"},
+		{text: "С 14:00 встреча", ok: true, start: at(13, 14, 0), end: now, message: "встреча"},
+		{text: "hello world"},
+		{text: "2h"},
+		{text: "-1h rollback"},
+		{text: "10:00-11:30"},
+		{text: "25:00-26:00 night"},
+		{text: "since 14:99 meeting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			log, ok := parseQuickLog(tt.text, now)
+
+			if ok != tt.ok {
+				t.Fatalf("got ok %v, want %v", ok, tt.ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if !log.StartWorkTime.Equal(tt.start) || !log.EndWorkTime.Equal(tt.end) || log.Message != tt.message {
+				t.Errorf("got %s - %s %q, want %s - %s %q", log.StartWorkTime, log.EndWorkTime, log.Message, tt.start, tt.end, tt.message)
+			}
+		})
+	}
+}
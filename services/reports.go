@@ -0,0 +1,364 @@
+package services
+
+import (
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	monthArgFormat = "2006-01"
+	// maxReportTasks keeps long period reports within the Telegram message limit.
+	maxReportTasks = 20
+)
+
+func (a *ApiHandler) HandleReportWeekCommand(userId int64, args string) {
+	day := time.Now()
+
+	if strings.TrimSpace(args) != "" {
+		var err error
+
+		day, err = time.ParseInLocation(time.DateOnly, strings.TrimSpace(args), time.Local)
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Укажите любой день недели в формате ГГГГ-ММ-ДД, например: /%s 2024-05-13. Без аргументов строится отчет за текущую неделю", constants.ReportWeekCommand))
+			return
+		}
+	}
+
+	from := utils.GetStartOfDay(day).AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	to := from.AddDate(0, 0, 6)
+
+	a.sendPeriodReport(userId, fmt.Sprintf("Отчет за неделю %s - %s", utils.GetOnlyDate(from), utils.GetOnlyDate(to)), from, to)
+}
+
+func (a *ApiHandler) HandleReportMonthCommand(userId int64, args string) {
+	month := time.Now()
+
+	if strings.TrimSpace(args) != "" {
+		var err error
+
+		month, err = time.ParseInLocation(monthArgFormat, strings.TrimSpace(args), time.Local)
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Укажите месяц в формате ГГГГ-ММ, например: /%s 2024-05. Без аргументов строится отчет за текущий месяц", constants.ReportMonthCommand))
+			return
+		}
+	}
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	to := from.AddDate(0, 1, -1)
+
+	a.sendPeriodReport(userId, fmt.Sprintf("Отчет за месяц %s", from.Format(monthArgFormat)), from, to)
+}
+
+// HandleReportCommand builds the report for the "from to" range, without
+// arguments the range is picked in the calendar.
+func (a *ApiHandler) HandleReportCommand(userId int64, args string) {
+	if strings.TrimSpace(args) != "" {
+		from, to, err := parseDateRangeArg(args, time.Now())
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Укажите период в формате ГГГГ-ММ-ДД, например: /%s 2024-05-01 2024-05-31. Без аргументов период выбирается в календаре", constants.ReportCommand))
+			return
+		}
+
+		a.sendRangeReport(userId, from, to)
+		return
+	}
+
+	_, err := a.setUserState(userId, constants.UserStateSelectReportFrom)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendReportCalendar(userId, "Выберите первый день периода", time.Now())
+}
+
+func (a *ApiHandler) HandleCallbackSelectReportFrom(userId int64, data string) {
+	if data == constants.CallbackParamCancel {
+		a.cancelReport(userId)
+		return
+	}
+
+	from, err := time.ParseInLocation(time.DateOnly, data, time.Local)
+
+	if err != nil {
+		logrus.Errorf("Failed to parse date: %v", err)
+		return
+	}
+
+	_, err = a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectReportTo
+		settings.ReportFrom = data
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendReportCalendar(userId, fmt.Sprintf("Начало периода: %s. Выберите последний день периода", data), from)
+}
+
+func (a *ApiHandler) HandleCallbackSelectReportTo(userId int64, data string) {
+	if data == constants.CallbackParamCancel {
+		a.cancelReport(userId)
+		return
+	}
+
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	from, to, err := parseDateRangeArg(settings.ReportFrom+" "+data, time.Now())
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Последний день должен быть не раньше %s и не дальше %d дней от него, выберите другой день", settings.ReportFrom, maxExportDays))
+		return
+	}
+
+	_, err = a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendRangeReport(userId, from, to)
+}
+
+func (a *ApiHandler) sendReportCalendar(userId int64, body string, month time.Time) {
+	dates, err := a.provider.GetDatesWithLogs(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get dates: %v", err)
+		return
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:   userId,
+		Body:     body,
+		Controls: []models.MarkupData{{Key: "Отмена", Value: constants.CallbackParamCancel}},
+		Calendar: &models.CalendarDto{Month: month, MarkedDates: dates},
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) sendRangeReport(userId int64, from time.Time, to time.Time) {
+	a.sendPeriodReport(userId, fmt.Sprintf("Отчет за период %s - %s", utils.GetOnlyDate(from), utils.GetOnlyDate(to)), from, to)
+}
+
+func (a *ApiHandler) cancelReport(userId int64) {
+	_, err := a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, "Построение отчета отменено")
+}
+
+func (a *ApiHandler) sendPeriodReport(userId int64, title string, from time.Time, to time.Time) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	report, err := a.BuildReport(userId, from, to)
+
+	if err != nil {
+		logrus.Errorf("Failed to build report: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, title+"\n"+constructPeriodReport(report, settings.Schedule, time.Now()))
+}
+
+// constructPeriodReport compares the logged time with the daily target. Only
+// work days up to today are expected to be logged, days off count when worked.
+func constructPeriodReport(report *models.ReportDto, schedule models.ScheduleSettingsDto, now time.Time) string {
+	dayTotals := map[string]time.Duration{}
+
+	for _, v := range report.Days {
+		dayTotals[v.Name] = v.Duration
+	}
+
+	messageText := "По дням:\n"
+	target := time.Duration(0)
+	today := utils.GetOnlyDate(now)
+
+	for day := report.From; !day.After(report.To); day = day.AddDate(0, 0, 1) {
+		date := utils.GetOnlyDate(day)
+		total, hasLogs := dayTotals[date]
+		isTarget := schedule.DailyTarget > 0 && isTargetDay(schedule, day.Weekday()) && date <= today
+
+		if !hasLogs && !isTarget {
+			continue
+		}
+
+		messageText += fmt.Sprintf("%s (%s): %s", date, weekdayNames[day.Weekday()], formatDuration(total))
+
+		if isTarget {
+			target += schedule.DailyTarget
+			messageText += fmt.Sprintf(" (%s)", formatSignedDuration(total-schedule.DailyTarget))
+		}
+
+		messageText += "\n"
+	}
+
+	tasks := report.Tasks
+
+	if len(tasks) > maxReportTasks {
+		tasks = tasks[:maxReportTasks]
+	}
+
+	messageText += fmt.Sprintf("Итого: %s", formatDuration(report.Total))
+
+	if schedule.DailyTarget > 0 {
+		messageText += fmt.Sprintf("\nНорма: %s, разница: %s", formatDuration(target), formatSignedDuration(report.Total-target))
+	}
+
+	messageText += constructTotalsSection("По задачам", tasks)
+
+	if len(report.Tasks) > len(tasks) {
+		messageText += fmt.Sprintf("\n...и еще задач: %d", len(report.Tasks)-len(tasks))
+	}
+
+	messageText += constructTotalsSection("По проектам", report.Projects)
+	messageText += constructTotalsSection("По тегам", report.Tags)
+	messageText += constructTotalsSection("По ключам задач", report.Issues)
+
+	return messageText
+}
+
+// isTargetDay falls back to Monday-Friday when no work days are configured.
+func isTargetDay(schedule models.ScheduleSettingsDto, weekday time.Weekday) bool {
+	if len(schedule.WorkDays) == 0 {
+		return weekday != time.Saturday && weekday != time.Sunday
+	}
+
+	for _, v := range schedule.WorkDays {
+		if v == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatSignedDuration(delta time.Duration) string {
+	if delta < 0 {
+		return "-" + formatDuration(-delta)
+	}
+
+	return "+" + formatDuration(delta)
+}
+
+type dayLogs struct {
+	date time.Time
+	logs []models.LogsInfoDto
+}
+
+// BuildReport aggregates the logs of the days between from and to inclusive.
+func (a *ApiHandler) BuildReport(userId int64, from time.Time, to time.Time) (*models.ReportDto, error) {
+	days, err := a.getLogsByRange(userId, from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ReportDto{From: utils.GetStartOfDay(from), To: utils.GetStartOfDay(to)}
+
+	var logs []models.LogsInfoDto
+
+	for _, day := range days {
+		dayTotal := time.Duration(0)
+
+		for _, v := range day.logs {
+			dayTotal += v.EndWorkTime.Sub(v.StartWorkTime)
+		}
+
+		report.Total += dayTotal
+		report.Days = append(report.Days, models.ReportTotalDto{Name: utils.GetOnlyDate(day.date), Duration: dayTotal})
+		logs = append(logs, day.logs...)
+	}
+
+	report.Tasks = aggregateByTask(logs)
+	report.Projects = aggregateByProject(logs)
+	report.Tags = aggregateByTag(logs)
+	report.Issues = aggregateByIssue(logs)
+
+	return report, nil
+}
+
+// getLogsByRange returns the days between from and to inclusive that have
+// logs, in chronological order.
+func (a *ApiHandler) getLogsByRange(userId int64, from time.Time, to time.Time) ([]dayLogs, error) {
+	dates, err := a.provider.GetDatesWithLogs(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(dates)
+
+	fromDate := utils.GetOnlyDate(from)
+	toDate := utils.GetOnlyDate(to)
+
+	var result []dayLogs
+
+	for _, date := range dates {
+		if date < fromDate || date > toDate {
+			continue
+		}
+
+		parsedDate, err := time.ParseInLocation(time.DateOnly, date, from.Location())
+
+		if err != nil {
+			return nil, err
+		}
+
+		logs, err := a.provider.GetLogRecords(userId, parsedDate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(logs) == 0 {
+			continue
+		}
+
+		sort.Slice(logs, func(i, k int) bool {
+			return logs[i].StartWorkTime.Before(logs[k].StartWorkTime)
+		})
+
+		result = append(result, dayLogs{date: parsedDate, logs: logs})
+	}
+
+	return result, nil
+}
+
+func aggregateByTask(logs []models.LogsInfoDto) []models.ReportTotalDto {
+	return aggregateDurations(logs, func(log models.LogsInfoDto) []string {
+		return []string{log.Message}
+	})
+}
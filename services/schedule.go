@@ -0,0 +1,139 @@
+package services
+
+import (
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReminderInterval = time.Hour
+	minReminderInterval     = 10 * time.Minute
+	maxWindowSearchDays     = 8
+)
+
+type reminderSchedule interface {
+	Next(after time.Time) time.Time
+	IsOpen(date time.Time) bool
+	NextOpen(date time.Time) time.Time
+}
+
+type dayRange struct {
+	from time.Duration
+	to   time.Duration
+}
+
+// workWindow limits reminders to working days, working hours and keeps them
+// out of the lunch break. Range borders themselves are open for reminders.
+type workWindow struct {
+	workDays   map[time.Weekday]bool
+	workHours  *dayRange
+	lunchBreak *dayRange
+}
+
+type intervalSchedule struct {
+	workWindow
+	interval time.Duration
+}
+
+func newReminderSchedule(settings models.ScheduleSettingsDto) reminderSchedule {
+	if settings.Cron != "" {
+		expressions, err := parseCronSchedule(settings.Cron)
+
+		if err == nil {
+			return &cronSchedule{workWindow: newWorkWindow(settings), expressions: expressions}
+		}
+
+		logrus.Warnf("Ignore invalid cron schedule %q: %v", settings.Cron, err)
+	}
+
+	interval := settings.ReminderInterval
+
+	if interval < minReminderInterval {
+		interval = defaultReminderInterval
+	}
+
+	return &intervalSchedule{workWindow: newWorkWindow(settings), interval: interval}
+}
+
+func newWorkWindow(settings models.ScheduleSettingsDto) workWindow {
+	window := workWindow{workDays: map[time.Weekday]bool{}}
+
+	for _, v := range settings.WorkDays {
+		window.workDays[v] = true
+	}
+
+	window.workHours = parseDayRange(settings.WorkHours)
+	window.lunchBreak = parseDayRange(settings.LunchBreak)
+
+	return window
+}
+
+func parseDayRange(dto *models.TimeRangeDto) *dayRange {
+	if dto == nil {
+		return nil
+	}
+
+	from, to, err := utils.ParseDayTimeRange(dto.From + "-" + dto.To)
+
+	if err != nil {
+		logrus.Warnf("Ignore invalid time range %s-%s: %v", dto.From, dto.To, err)
+		return nil
+	}
+
+	return &dayRange{from: from, to: to}
+}
+
+func (s *intervalSchedule) Next(after time.Time) time.Time {
+	return s.NextOpen(after.Add(s.interval))
+}
+
+func (w workWindow) IsOpen(date time.Time) bool {
+	if len(w.workDays) > 0 && !w.workDays[date.Weekday()] {
+		return false
+	}
+
+	offset := date.Sub(utils.GetStartOfDay(date))
+
+	if w.workHours != nil && (offset < w.workHours.from || offset > w.workHours.to) {
+		return false
+	}
+
+	if w.lunchBreak != nil && offset > w.lunchBreak.from && offset < w.lunchBreak.to {
+		return false
+	}
+
+	return true
+}
+
+func (w workWindow) NextOpen(date time.Time) time.Time {
+	for i := 0; i < maxWindowSearchDays*3; i++ {
+		if w.IsOpen(date) {
+			return date
+		}
+
+		dayStart := utils.GetStartOfDay(date)
+		offset := date.Sub(dayStart)
+		isWorkDay := len(w.workDays) == 0 || w.workDays[date.Weekday()]
+
+		if isWorkDay && w.workHours != nil && offset < w.workHours.from {
+			date = dayStart.Add(w.workHours.from)
+			continue
+		}
+
+		if isWorkDay && w.lunchBreak != nil && offset > w.lunchBreak.from && offset < w.lunchBreak.to {
+			date = dayStart.Add(w.lunchBreak.to)
+			continue
+		}
+
+		date = utils.GetStartOfDay(dayStart.AddDate(0, 0, 1))
+
+		if w.workHours != nil {
+			date = date.Add(w.workHours.from)
+		}
+	}
+
+	return date
+}
@@ -2,128 +2,476 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"logs-aggregator-bot/constants"
 	"logs-aggregator-bot/models"
 	"logs-aggregator-bot/provider"
 	"logs-aggregator-bot/utils"
+	"sync"
 	"time"
 )
 
 type tgClient interface {
 	SendMessage(req *models.SendNotificationRequest) error
+	SendDocument(req *models.SendDocumentRequest) error
 }
 
+var ErrWorkDayNotStarted = errors.New("work day is not started")
+
+const (
+	reminderRetryDelay = time.Minute
+	// scheduleRefreshPeriod bounds how long a changed schedule waits to be picked up.
+	scheduleRefreshPeriod = 5 * time.Minute
+)
+
 type SchedulerService struct {
-	provider *provider.JsonStorageProvider
+	provider provider.StorageProvider
 	tgClient tgClient
+	webhooks *WebhookService
+	mu       sync.Mutex
+	running  map[int64]chan struct{}
 }
 
-func NewSchedulerService(provider *provider.JsonStorageProvider, tgCli tgClient) *SchedulerService {
-	return &SchedulerService{provider, tgCli}
+func NewSchedulerService(provider provider.StorageProvider, tgCli tgClient, webhooks *WebhookService) *SchedulerService {
+	return &SchedulerService{provider: provider, tgClient: tgCli, webhooks: webhooks, running: map[int64]chan struct{}{}}
+}
+
+func (s *SchedulerService) IsRunning(userId int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exist := s.running[userId]
+	return exist
 }
 
-func (s *SchedulerService) Start(ctx context.Context, doneChan <-chan struct{}) {
-	settings, err := s.provider.GetUserSettings()
+// Start opens a workday for the user unless one is still open and launches the
+// reminder loop.
+func (s *SchedulerService) Start(ctx context.Context, userId int64) error {
+	now := time.Now()
+	isStarted := false
+
+	err := s.finishMissedWorkDay(userId, now)
 
 	if err != nil {
-		logrus.Errorf("Failed to get user settings: %v", err)
-		return
+		return err
 	}
 
-	if settings.WorkStarted.Day() == time.Now().Day() {
-		logrus.Warn("Scheduler is already running, skip request")
-		return
+	settings, err := s.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		isStarted = !isWorkDayOpen(settings, now)
+
+		if isStarted {
+			settings.WorkStarted = now
+			settings.LastReminderAt = now
+		}
+
+		settings.WorkFinished = time.Time{}
+		settings.SummaryPending = false
+		return nil
+	})
+
+	if err != nil {
+		return err
 	}
 
-	settings.WorkStarted = time.Now()
-	err = s.provider.SetUserSettings(settings)
+	if isStarted {
+		s.webhooks.Publish(models.WebhookEventDto{
+			Type:    constants.WebhookEventWorkDayStarted,
+			UserId:  userId,
+			WorkDay: &models.WorkDayDto{Started: settings.WorkStarted},
+		})
+	}
+
+	s.launch(ctx, userId)
+	return nil
+}
+
+// Resume restarts reminder loops for workdays left open by a previous run.
+func (s *SchedulerService) Resume(ctx context.Context) error {
+	users, err := s.provider.GetUsers()
 
 	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, v := range users {
+		if !v.IsActive() {
+			continue
+		}
+
+		if !isWorkDayOpen(&v, now) {
+			err = s.finishMissedWorkDay(v.UserId, now)
+
+			if err != nil {
+				logrus.Errorf("Failed to finish workday of user %d: %v", v.UserId, err)
+			}
+
+			continue
+		}
+
+		logrus.Infof("Resuming workday of user %d started at %s", v.UserId, utils.GetOnlyTime(v.WorkStarted))
+		s.launch(ctx, v.UserId)
+	}
+
+	return nil
+}
+
+// Stop closes the workday silently, without the final prompt and summary.
+func (s *SchedulerService) Stop(userId int64) {
+	_, err := s.closeWorkDay(userId, time.Now())
+
+	if err != nil && !errors.Is(err, ErrWorkDayNotStarted) {
 		logrus.Errorf("Failed to set user settings: %v", err)
-		return
+	}
+}
+
+// finishMissedWorkDay closes a workday left open past its end, e.g. while the
+// bot was down, and sends its summary.
+func (s *SchedulerService) finishMissedWorkDay(userId int64, now time.Time) error {
+	settings, err := s.provider.GetUserSettings(userId)
+
+	if err != nil {
+		return err
+	}
+
+	if settings.WorkStarted.IsZero() || !settings.WorkFinished.Before(settings.WorkStarted) || isWorkDayOpen(settings, now) {
+		return nil
+	}
+
+	_, err = s.closeWorkDay(userId, getWorkDayEnd(settings))
+
+	if err != nil {
+		return err
+	}
+
+	return s.SendDailySummary(userId)
+}
+
+// FinishWorkDay closes the workday, asks to log the time left since the last
+// entry and sends the daily summary once everything is logged.
+func (s *SchedulerService) FinishWorkDay(userId int64, finishedAt time.Time) error {
+	settings, err := s.closeWorkDay(userId, finishedAt)
+
+	if err != nil {
+		return err
+	}
+
+	logs, err := s.provider.GetLogRecords(userId, settings.WorkStarted)
+
+	if err != nil {
+		return err
+	}
+
+	loggedTo := settings.WorkStarted
+
+	if len(logs) > 0 {
+		loggedTo = getLastLog(logs).EndWorkTime
+	}
+
+	if finishedAt.Sub(loggedTo) >= time.Minute {
+		return s.promptWorkLog(userId, finishedAt, true)
+	}
+
+	return s.SendDailySummary(userId)
+}
+
+func (s *SchedulerService) SendDailySummary(userId int64) error {
+	settings, err := s.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.SummaryPending = false
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	logs, err := s.provider.GetLogRecords(userId, settings.WorkStarted)
+
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Итоги рабочего дня %s: логов нет", utils.GetOnlyDate(settings.WorkStarted))
+
+	if len(logs) > 0 {
+		body = fmt.Sprintf("Итоги рабочего дня %s\n%s", utils.GetOnlyDate(settings.WorkStarted), constructLogsTable(logs))
+	}
+
+	return s.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: settings.UserId,
+		Body:   body,
+	})
+}
+
+func (s *SchedulerService) closeWorkDay(userId int64, finishedAt time.Time) (*models.UserSettingsDto, error) {
+	s.mu.Lock()
+	doneChan, exist := s.running[userId]
+
+	if exist {
+		close(doneChan)
+		delete(s.running, userId)
+	}
+
+	s.mu.Unlock()
+
+	settings, err := s.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		if !isWorkDayOpen(settings, settings.WorkStarted) {
+			return ErrWorkDayNotStarted
+		}
+
+		settings.WorkFinished = finishedAt
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.webhooks.Publish(models.WebhookEventDto{
+		Type:    constants.WebhookEventWorkDayFinished,
+		UserId:  userId,
+		WorkDay: &models.WorkDayDto{Started: settings.WorkStarted, Finished: settings.WorkFinished},
+	})
+
+	return settings, nil
+}
+
+func (s *SchedulerService) launch(ctx context.Context, userId int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doneChan, exist := s.running[userId]; exist {
+		close(doneChan)
 	}
 
-	notificationTicker := time.NewTicker(time.Hour)
+	doneChan := make(chan struct{})
+	s.running[userId] = doneChan
+
+	go s.run(ctx, userId, doneChan)
+}
+
+func (s *SchedulerService) release(userId int64, doneChan chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[userId] == doneChan {
+		delete(s.running, userId)
+	}
+}
+
+func (s *SchedulerService) run(ctx context.Context, userId int64, doneChan chan struct{}) {
+	retryAt := time.Time{}
+
 	for {
+		settings, err := s.provider.GetUserSettings(userId)
+
+		if err != nil {
+			logrus.Errorf("Failed to get user settings: %v", err)
+			s.release(userId, doneChan)
+			return
+		}
+
+		lastReminder := settings.LastReminderAt
+
+		if lastReminder.IsZero() {
+			lastReminder = settings.WorkStarted
+		}
+
+		schedule := newReminderSchedule(settings.Schedule)
+		nextReminder := schedule.Next(lastReminder)
+		now := time.Now()
+
+		if nextReminder.IsZero() {
+			logrus.Warnf("Schedule of user %d has no upcoming reminders", userId)
+			nextReminder = now.Add(scheduleRefreshPeriod)
+		}
+
+		if nextReminder.Before(now) {
+			logrus.Infof("User %d missed reminder at %s, catching up", userId, nextReminder.Format(time.DateTime))
+			nextReminder = schedule.NextOpen(now)
+		}
+
+		if nextReminder.Before(retryAt) {
+			nextReminder = schedule.NextOpen(retryAt)
+		}
+
+		target := nextReminder
+		isStop := false
+
+		if stopAt, ok := getAutoStopTime(settings); ok && !stopAt.After(target) {
+			target = stopAt
+			isStop = true
+		}
+
+		wait := time.Until(target)
+		isRefresh := wait > scheduleRefreshPeriod
+
+		if isRefresh {
+			wait = scheduleRefreshPeriod
+		}
+
+		notificationTimer := time.NewTimer(wait)
+
 		select {
 		case <-ctx.Done():
-			notificationTicker.Stop()
+			notificationTimer.Stop()
+			s.release(userId, doneChan)
 			return
 		case <-doneChan:
-			notificationTicker.Stop()
+			notificationTimer.Stop()
 			return
-		case <-notificationTicker.C:
-			settings, err := s.provider.GetUserSettings()
-
-			if err != nil {
-				logrus.Errorf("Failed to get user settings: %v", err)
+		case <-notificationTimer.C:
+			if isRefresh {
 				continue
 			}
 
-			settings.CurrentState = constants.UserStateSelectLogType
-			settings.NeedWorkLogTo = time.Now()
+			if isStop {
+				err = s.FinishWorkDay(userId, target)
 
-			err = s.provider.SetUserSettings(settings)
+				if err != nil {
+					logrus.Errorf("Failed to finish workday of user %d: %v", userId, err)
+				}
 
-			if err != nil {
-				logrus.Errorf("Failed to set user settings: %v", err)
-				continue
+				s.release(userId, doneChan)
+				return
 			}
 
-			logs, err := s.provider.GetLogRecords(settings.WorkStarted)
+			err = s.remind(userId)
 
 			if err != nil {
-				logrus.Errorf("Failed to get logs: %v", err)
-				continue
+				logrus.Errorf("Failed to remind user %d: %v", userId, err)
+				retryAt = time.Now().Add(reminderRetryDelay)
 			}
+		}
+	}
+}
 
-			if len(logs) == 0 {
-				settings.CurrentState = constants.UserStateSelectNewLogMessage
+func (s *SchedulerService) remind(userId int64) error {
+	return s.promptWorkLog(userId, time.Now(), false)
+}
 
-				err = s.provider.SetUserSettings(settings)
+// promptWorkLog asks the user to log the time up to the given moment. The
+// final prompt of a workday is followed by the daily summary.
+func (s *SchedulerService) promptWorkLog(userId int64, until time.Time, isFinal bool) error {
+	settings, err := s.provider.GetUserSettings(userId)
 
-				if err != nil {
-					logrus.Errorf("Failed to set user settings: %v", err)
-					continue
-				}
+	if err != nil {
+		return fmt.Errorf("get user settings: %w", err)
+	}
 
-				err = s.tgClient.SendMessage(&models.SendNotificationRequest{
-					ChatId: settings.UserId,
-					Body:   fmt.Sprintf("Залогайте вашу работу за период: %s-%s", utils.GetOnlyTime(settings.WorkStarted), utils.GetOnlyTime(settings.NeedWorkLogTo)),
-				})
+	logs, err := s.provider.GetLogRecords(userId, settings.WorkStarted)
 
-				if err != nil {
-					logrus.Errorf("Failed to send message to user: %v", err)
-					continue
-				}
+	if err != nil {
+		return fmt.Errorf("get logs: %w", err)
+	}
 
-				continue
-			}
+	settings, err = s.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.CurrentState = constants.UserStateSelectLogType
+		settings.NeedWorkLogTo = until
+		settings.PendingMessage = ""
+		settings.SummaryPending = isFinal
 
-			lastLog := getLastLog(logs)
-
-			err = s.tgClient.SendMessage(&models.SendNotificationRequest{
-				ChatId: settings.UserId,
-				Body:   fmt.Sprintf("Ваш последний ворк-лог по работе: %s, время начала: %s,  желаете ли вы продолжить его по времени?", lastLog.Message, utils.GetOnlyTime(lastLog.StartWorkTime)),
-				Markup: []models.MarkupData{
-					{
-						Key:   "Да",
-						Value: constants.CallbackParamContinueOldLog,
-					},
-					{
-						Key:   "Нет",
-						Value: constants.CallbackParamCreateNewLog,
-					},
-				},
-			})
+		if !isFinal {
+			settings.LastReminderAt = until
+		}
 
-			if err != nil {
-				logrus.Errorf("Failed to send message to user: %v", err)
-				continue
-			}
+		if len(logs) == 0 {
+			settings.CurrentState = constants.UserStateSelectNewLogMessage
+		}
+
+		return nil
+	})
 
+	if err != nil {
+		return fmt.Errorf("update user settings: %w", err)
+	}
+
+	prefix := ""
+
+	if isFinal {
+		prefix = "Рабочий день завершен. "
+	}
+
+	if len(logs) == 0 {
+		err = s.tgClient.SendMessage(&models.SendNotificationRequest{
+			ChatId: settings.UserId,
+			Body:   fmt.Sprintf("%sЗалогайте вашу работу за период: %s-%s", prefix, utils.GetOnlyTime(settings.WorkStarted), utils.GetOnlyTime(settings.NeedWorkLogTo)),
+		})
+
+		if err != nil {
+			logrus.Errorf("Failed to send message to user: %v", err)
 		}
+
+		return nil
+	}
+
+	lastLog := getLastLog(logs)
+
+	err = s.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId: settings.UserId,
+		Body:   fmt.Sprintf("%sВаш последний ворк-лог по работе: %s, время начала: %s,  желаете ли вы продолжить его по времени?", prefix, lastLog.Message, utils.GetOnlyTime(lastLog.StartWorkTime)),
+		Markup: []models.MarkupData{
+			{
+				Key:   "Да",
+				Value: constants.CallbackParamContinueOldLog,
+			},
+			{
+				Key:   "Нет",
+				Value: constants.CallbackParamCreateNewLog,
+			},
+		},
+		Columns: 2,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message to user: %v", err)
+	}
+
+	return nil
+}
+
+// getAutoStopTime returns the first stop time after the start of the workday,
+// a workday started late stops at the stop time of the next day.
+func getAutoStopTime(settings *models.UserSettingsDto) (time.Time, bool) {
+	if settings.Schedule.AutoStopTime == "" {
+		return time.Time{}, false
+	}
+
+	offset, err := utils.ParseDayTime(settings.Schedule.AutoStopTime)
+
+	if err != nil {
+		logrus.Warnf("Ignore invalid auto stop time %q: %v", settings.Schedule.AutoStopTime, err)
+		return time.Time{}, false
+	}
+
+	day := utils.GetStartOfDay(settings.WorkStarted)
+
+	if !day.Add(offset).After(settings.WorkStarted) {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return day.Add(offset), true
+}
+
+// getWorkDayEnd returns the auto-stop time of the workday, without one the
+// workday ends with its day.
+func getWorkDayEnd(settings *models.UserSettingsDto) time.Time {
+	if stopAt, ok := getAutoStopTime(settings); ok {
+		return stopAt
 	}
+
+	return utils.GetStartOfDay(settings.WorkStarted).AddDate(0, 0, 1)
+}
+
+// isWorkDayOpen reports whether the workday is neither finished nor past its
+// end, a workday stopping the next day stays open over midnight.
+func isWorkDayOpen(settings *models.UserSettingsDto, now time.Time) bool {
+	if settings.WorkStarted.IsZero() || !settings.WorkFinished.Before(settings.WorkStarted) {
+		return false
+	}
+
+	return now.Before(getWorkDayEnd(settings))
 }
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"logs-aggregator-bot/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsWorkDayOpen(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		started  time.Time
+		finished time.Time
+		autoStop string
+		now      time.Time
+		want     bool
+	}{
+		{name: "not started", now: at(13, 10, 0)},
+		{name: "started today", started: at(13, 9, 0), now: at(13, 10, 0), want: true},
+		{name: "finished", started: at(13, 9, 0), finished: at(13, 9, 30), now: at(13, 10, 0)},
+		{name: "finished the day before", started: at(13, 9, 0), finished: at(12, 18, 0), now: at(13, 10, 0), want: true},
+		{name: "without auto stop after midnight", started: at(13, 23, 0), now: at(14, 0, 30)},
+		{name: "before auto stop", started: at(13, 9, 0), autoStop: "18:00", now: at(13, 17, 59), want: true},
+		{name: "after auto stop", started: at(13, 9, 0), autoStop: "18:00", now: at(13, 18, 0)},
+		{name: "late start after midnight", started: at(13, 23, 0), autoStop: "18:00", now: at(14, 0, 30), want: true},
+		{name: "late start after next auto stop", started: at(13, 23, 0), autoStop: "18:00", now: at(14, 18, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &models.UserSettingsDto{WorkStarted: tt.started, WorkFinished: tt.finished}
+			settings.Schedule.AutoStopTime = tt.autoStop
+
+			if got := isWorkDayOpen(settings, tt.now); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerResume(t *testing.T) {
+	now := time.Now()
+
+	// The first workday stops in an hour, the second one stopped while the bot
+	// was down.
+	open := models.UserSettingsDto{UserId: 1, WorkStarted: now.Add(-2 * time.Hour), LastReminderAt: now}
+	open.Schedule.AutoStopTime = now.Add(time.Hour).Format("15:04")
+
+	missed := models.UserSettingsDto{UserId: 2, WorkStarted: now.Add(-48 * time.Hour)}
+	missed.Schedule.AutoStopTime = now.Add(-47 * time.Hour).Format("15:04")
+
+	storage := newFakeStorage(open, missed)
+	tgCli := &fakeTgClient{}
+	scheduler := NewSchedulerService(storage, tgCli, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := scheduler.Resume(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !scheduler.IsRunning(open.UserId) || scheduler.IsRunning(missed.UserId) {
+		t.Errorf("got running %v and %v, want only the open workday", scheduler.IsRunning(open.UserId), scheduler.IsRunning(missed.UserId))
+	}
+
+	settings, err := storage.GetUserSettings(missed.UserId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopAt, _ := getAutoStopTime(&missed)
+
+	if !settings.WorkFinished.Equal(stopAt) {
+		t.Errorf("got missed workday finished at %s, want %s", settings.WorkFinished, stopAt)
+	}
+
+	if len(tgCli.messages) != 1 || tgCli.messages[0].ChatId != missed.UserId || !strings.HasPrefix(tgCli.messages[0].Body, "Итоги рабочего дня") {
+		t.Errorf("got messages %+v, want the summary of the missed workday", tgCli.messages)
+	}
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	disableSettingArg = "off"
+	maxDailyTarget    = 24 * time.Hour
+	defaultTimeStep   = 10 * time.Minute
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "пн",
+	time.Tuesday:   "вт",
+	time.Wednesday: "ср",
+	time.Thursday:  "чт",
+	time.Friday:    "пт",
+	time.Saturday:  "сб",
+	time.Sunday:    "вс",
+}
+
+func (a *ApiHandler) HandleSettingsCommand(userId int64) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	a.sendMessage(userId, formatScheduleSettings(settings.Schedule))
+}
+
+func (a *ApiHandler) HandleSetIntervalCommand(userId int64, args string) {
+	interval, err := time.ParseDuration(strings.TrimSpace(args))
+
+	if err != nil || interval < minReminderInterval {
+		a.sendMessage(userId, fmt.Sprintf("Укажите интервал не меньше %s, например: /%s 1h30m", minReminderInterval, constants.SetIntervalCommand))
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.ReminderInterval = interval
+	})
+}
+
+func (a *ApiHandler) HandleSetCronCommand(userId int64, args string) {
+	args = strings.TrimSpace(args)
+
+	if args == disableSettingArg {
+		a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+			schedule.Cron = ""
+		})
+		return
+	}
+
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	schedule := settings.Schedule
+	schedule.Cron = args
+
+	err = validateCronSchedule(schedule)
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Некорректное расписание: %v\nФормат: минуты часы день_месяца месяц день_недели, несколько выражений через \"%s\", например:\n/%s 0 11,14 * * 1-5; 45 17 * * 1-5\n/%s %s чтобы вернуться к интервалу",
+			err, cronExpressionSeparator, constants.SetCronCommand, constants.SetCronCommand, disableSettingArg))
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.Cron = args
+	})
+}
+
+func (a *ApiHandler) HandleSetWorkHoursCommand(userId int64, args string) {
+	timeRange, ok := a.parseTimeRangeArg(userId, args, constants.SetWorkHoursCommand, "09:00-18:00")
+
+	if !ok {
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.WorkHours = timeRange
+	})
+}
+
+func (a *ApiHandler) HandleSetLunchCommand(userId int64, args string) {
+	timeRange, ok := a.parseTimeRangeArg(userId, args, constants.SetLunchCommand, "13:00-14:00")
+
+	if !ok {
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.LunchBreak = timeRange
+	})
+}
+
+func (a *ApiHandler) HandleSetAutoStopCommand(userId int64, args string) {
+	args = strings.TrimSpace(args)
+
+	if args == disableSettingArg {
+		a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+			schedule.AutoStopTime = ""
+		})
+		return
+	}
+
+	_, err := utils.ParseDayTime(args)
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Укажите время автоматического завершения дня, например: /%s 18:30, или /%s %s чтобы отключить", constants.SetAutoStopCommand, constants.SetAutoStopCommand, disableSettingArg))
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.AutoStopTime = args
+	})
+}
+
+func (a *ApiHandler) HandleSetTargetCommand(userId int64, args string) {
+	args = strings.TrimSpace(args)
+
+	if args == disableSettingArg {
+		a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+			schedule.DailyTarget = 0
+		})
+		return
+	}
+
+	target, err := time.ParseDuration(args)
+
+	if err != nil || target <= 0 || target > maxDailyTarget {
+		a.sendMessage(userId, fmt.Sprintf("Укажите норму часов в день, например: /%s 8h или /%s 7h30m, или /%s %s чтобы отключить",
+			constants.SetTargetCommand, constants.SetTargetCommand, constants.SetTargetCommand, disableSettingArg))
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.DailyTarget = target
+	})
+}
+
+func (a *ApiHandler) HandleSetTimeStepCommand(userId int64, args string) {
+	args = strings.TrimSpace(args)
+
+	if args == disableSettingArg {
+		a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+			schedule.TimeStep = 0
+		})
+		return
+	}
+
+	step, err := time.ParseDuration(args)
+
+	if err != nil || !isValidTimeStep(step) {
+		a.sendMessage(userId, fmt.Sprintf("Укажите шаг выбора времени в минутах, на который делится час, например: /%s 5m или /%s 15m, или /%s %s чтобы вернуть %s",
+			constants.SetTimeStepCommand, constants.SetTimeStepCommand, constants.SetTimeStepCommand, disableSettingArg, formatDuration(defaultTimeStep)))
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.TimeStep = step
+	})
+}
+
+func (a *ApiHandler) HandleSetWorkDaysCommand(userId int64, args string) {
+	args = strings.TrimSpace(args)
+
+	if args == disableSettingArg {
+		a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+			schedule.WorkDays = nil
+		})
+		return
+	}
+
+	workDays, err := parseWeekdays(args)
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Укажите дни недели числами от 1 (пн) до 7 (вс), например: /%s 1-5 или /%s 1,3,5", constants.SetWorkDaysCommand, constants.SetWorkDaysCommand))
+		return
+	}
+
+	a.updateSchedule(userId, func(schedule *models.ScheduleSettingsDto) {
+		schedule.WorkDays = workDays
+	})
+}
+
+// UpdateSchedule replaces the whole schedule after validating it.
+func (a *ApiHandler) UpdateSchedule(userId int64, schedule models.ScheduleSettingsDto) (*models.UserSettingsDto, error) {
+	err := validateSchedule(schedule)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	return a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		settings.Schedule = schedule
+		return nil
+	})
+}
+
+// validateCronSchedule rejects a cron schedule never firing within the working
+// hours and days, the reminders would silently stop otherwise.
+func validateCronSchedule(schedule models.ScheduleSettingsDto) error {
+	expressions, err := parseCronSchedule(schedule.Cron)
+
+	if err != nil {
+		return err
+	}
+
+	if (&cronSchedule{workWindow: newWorkWindow(schedule), expressions: expressions}).Next(time.Now()).IsZero() {
+		return errors.New("schedule never fires within the working hours and days")
+	}
+
+	return nil
+}
+
+func validateSchedule(schedule models.ScheduleSettingsDto) error {
+	if schedule.ReminderInterval != 0 && schedule.ReminderInterval < minReminderInterval {
+		return fmt.Errorf("reminder interval must be at least %s", minReminderInterval)
+	}
+
+	if schedule.Cron != "" {
+		err := validateCronSchedule(schedule)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, timeRange := range []*models.TimeRangeDto{schedule.WorkHours, schedule.LunchBreak} {
+		if timeRange == nil {
+			continue
+		}
+
+		_, _, err := utils.ParseDayTimeRange(timeRange.From + "-" + timeRange.To)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if schedule.AutoStopTime != "" {
+		_, err := utils.ParseDayTime(schedule.AutoStopTime)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if schedule.DailyTarget < 0 || schedule.DailyTarget > maxDailyTarget {
+		return fmt.Errorf("daily target must be between 0 and %s", maxDailyTarget)
+	}
+
+	if schedule.TimeStep != 0 && !isValidTimeStep(schedule.TimeStep) {
+		return fmt.Errorf("time step must be a whole number of minutes dividing an hour")
+	}
+
+	for _, day := range schedule.WorkDays {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("invalid weekday %d", day)
+		}
+	}
+
+	return nil
+}
+
+func (a *ApiHandler) updateSchedule(userId int64, update func(schedule *models.ScheduleSettingsDto)) {
+	settings, err := a.provider.UpdateUserSettings(userId, func(settings *models.UserSettingsDto) error {
+		update(&settings.Schedule)
+		return nil
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to update user settings: %v", err)
+		return
+	}
+
+	body := "Настройки сохранены\n" + formatScheduleSettings(settings.Schedule)
+
+	// Working hours changed after the cron schedule may leave no reminders.
+	if newReminderSchedule(settings.Schedule).Next(time.Now()).IsZero() {
+		body += "\nВнимание: с этими настройками напоминания не будут приходить"
+	}
+
+	a.sendMessage(userId, body)
+}
+
+func (a *ApiHandler) parseTimeRangeArg(userId int64, args string, command constants.Commands, example string) (*models.TimeRangeDto, bool) {
+	args = strings.TrimSpace(args)
+
+	if args == disableSettingArg {
+		return nil, true
+	}
+
+	_, _, err := utils.ParseDayTimeRange(args)
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Укажите интервал времени, например: /%s %s, или /%s %s чтобы отключить", command, example, command, disableSettingArg))
+		return nil, false
+	}
+
+	from, to, _ := strings.Cut(args, "-")
+
+	return &models.TimeRangeDto{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}, true
+}
+
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	selected := map[time.Weekday]bool{}
+
+	for _, part := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+
+		if !isRange {
+			to = from
+		}
+
+		fromDay, err := strconv.Atoi(strings.TrimSpace(from))
+
+		if err != nil {
+			return nil, err
+		}
+
+		toDay, err := strconv.Atoi(strings.TrimSpace(to))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if fromDay < 1 || toDay > 7 || fromDay > toDay {
+			return nil, fmt.Errorf("invalid weekday range %q", part)
+		}
+
+		for day := fromDay; day <= toDay; day++ {
+			selected[time.Weekday(day%7)] = true
+		}
+	}
+
+	var workDays []time.Weekday
+
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if selected[day] {
+			workDays = append(workDays, day)
+		}
+	}
+
+	return workDays, nil
+}
+
+func formatScheduleSettings(schedule models.ScheduleSettingsDto) string {
+	interval := schedule.ReminderInterval
+
+	if interval < minReminderInterval {
+		interval = defaultReminderInterval
+	}
+
+	messageText := fmt.Sprintf("Интервал напоминаний: %s\n", interval)
+
+	if schedule.Cron != "" {
+		messageText = fmt.Sprintf("Расписание напоминаний: %s\n", schedule.Cron)
+	}
+
+	messageText += fmt.Sprintf("Рабочие часы: %s\n", formatTimeRange(schedule.WorkHours))
+	messageText += fmt.Sprintf("Обед: %s\n", formatTimeRange(schedule.LunchBreak))
+
+	if schedule.AutoStopTime == "" {
+		messageText += "Автозавершение дня: не задано\n"
+	} else {
+		messageText += fmt.Sprintf("Автозавершение дня: %s\n", schedule.AutoStopTime)
+	}
+
+	if schedule.DailyTarget == 0 {
+		messageText += "Норма в день: не задана\n"
+	} else {
+		messageText += fmt.Sprintf("Норма в день: %s\n", formatDuration(schedule.DailyTarget))
+	}
+
+	messageText += fmt.Sprintf("Шаг выбора времени: %s\n", formatDuration(getTimeStep(schedule)))
+
+	if len(schedule.WorkDays) == 0 {
+		messageText += "Рабочие дни: все\n"
+	} else {
+		var days []string
+
+		for _, v := range schedule.WorkDays {
+			days = append(days, weekdayNames[v])
+		}
+
+		messageText += fmt.Sprintf("Рабочие дни: %s\n", strings.Join(days, ", "))
+	}
+
+	return messageText
+}
+
+func formatTimeRange(timeRange *models.TimeRangeDto) string {
+	if timeRange == nil {
+		return "не задано"
+	}
+
+	return fmt.Sprintf("%s-%s", timeRange.From, timeRange.To)
+}
+
+func isValidTimeStep(step time.Duration) bool {
+	return step >= time.Minute && step%time.Minute == 0 && time.Hour%step == 0
+}
+
+func getTimeStep(schedule models.ScheduleSettingsDto) time.Duration {
+	if schedule.TimeStep == 0 {
+		return defaultTimeStep
+	}
+
+	return schedule.TimeStep
+}
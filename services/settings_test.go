@@ -0,0 +1,64 @@
+package services
+
+import (
+	"logs-aggregator-bot/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleSetCronCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    string
+		message string
+	}{
+		{name: "within working hours", args: "0 11,14 * * 1-5", want: "0 11,14 * * 1-5", message: "Настройки сохранены"},
+		{name: "outside of working hours", args: "30 20 * * *", want: "0 10 * * *", message: "Некорректное расписание: schedule never fires"},
+		{name: "invalid", args: "0 25 * * *", want: "0 10 * * *", message: "Некорректное расписание"},
+		{name: "disabled", args: "off", message: "Настройки сохранены"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := models.UserSettingsDto{UserId: 1}
+			settings.Schedule.Cron = "0 10 * * *"
+			settings.Schedule.WorkHours = &models.TimeRangeDto{From: "09:00", To: "18:00"}
+
+			storage := newFakeStorage(settings)
+			tgCli := &fakeTgClient{}
+			handler := NewApiHandler(storage, tgCli, nil, nil, nil, "")
+
+			handler.HandleSetCronCommand(settings.UserId, tt.args)
+
+			stored, err := storage.GetUserSettings(settings.UserId)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Schedule.Cron != tt.want {
+				t.Errorf("got cron %q, want %q", stored.Schedule.Cron, tt.want)
+			}
+
+			if len(tgCli.messages) != 1 || !strings.HasPrefix(tgCli.messages[0].Body, tt.message) {
+				t.Errorf("got messages %+v, want %q", tgCli.messages, tt.message)
+			}
+		})
+	}
+}
+
+func TestValidateScheduleRejectsSilentCron(t *testing.T) {
+	schedule := models.ScheduleSettingsDto{Cron: "0 10 * * 6"}
+
+	if err := validateSchedule(schedule); err != nil {
+		t.Errorf("got error %v for a schedule firing on saturdays", err)
+	}
+
+	schedule.WorkDays = []time.Weekday{time.Monday, time.Friday}
+
+	if err := validateSchedule(schedule); err == nil {
+		t.Error("expected an error for a schedule firing on days off only")
+	}
+}
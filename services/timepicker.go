@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sendLogTimeSelection asks for the end of the log being filled, either in the
+// time picker or typed as HH:MM.
+func (a *ApiHandler) sendLogTimeSelection(settings *models.UserSettingsDto, body string) {
+	from, to, err := a.getLogTimeBounds(settings)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:     settings.UserId,
+		Body:       body + "\nВыберите час и минуты или введите время в формате ЧЧ:ММ",
+		TimePicker: &models.TimePickerDto{From: from, To: to, Step: getTimeStep(settings.Schedule)},
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+// HandleLogTimeValue accepts the typed end time and passes it on as if it was
+// picked from the keyboard.
+func (a *ApiHandler) HandleLogTimeValue(userId int64, value string) {
+	settings, err := a.provider.GetUserSettings(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get user settings: %v", err)
+		return
+	}
+
+	from, to, err := a.getLogTimeBounds(settings)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	endTime, ok := parseLogTime(value, from, to)
+
+	if !ok {
+		a.sendMessage(userId, fmt.Sprintf("Некорректное время, введите время в формате ЧЧ:ММ позже %s и не позже %s", from.Format("15:04"), to.Format("15:04")))
+		return
+	}
+
+	data := fmt.Sprint(endTime.UnixMilli())
+
+	switch settings.CurrentState {
+	case constants.UserStateSelectNewLogDate:
+		a.HandleCallbackSelectNewLogDate(userId, data)
+	case constants.UserStateSelectOldLogDate:
+		a.HandleCallbackSelectOldLogDate(userId, data)
+	}
+}
+
+// getLogTimeBounds returns the end of the last log of the workday, or its start
+// when nothing is logged yet, and the time the log has to be filled up to.
+func (a *ApiHandler) getLogTimeBounds(settings *models.UserSettingsDto) (time.Time, time.Time, error) {
+	logs, err := a.provider.GetLogRecords(settings.UserId, settings.WorkStarted)
+
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if len(logs) == 0 {
+		return settings.WorkStarted, settings.NeedWorkLogTo, nil
+	}
+
+	return getLastLog(logs).EndWorkTime, settings.NeedWorkLogTo, nil
+}
+
+// parseLogTime puts the typed HH:MM on the day of either bound, so a period
+// crossing midnight can still be filled.
+func parseLogTime(value string, from time.Time, to time.Time) (time.Time, bool) {
+	offset, err := utils.ParseDayTime(value)
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	for _, day := range []time.Time{to, from} {
+		parsed := utils.GetStartOfDay(day).Add(offset)
+
+		if parsed.After(from) && !parsed.After(to) {
+			return parsed, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogTime(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		from  time.Time
+		to    time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{name: "within the day", value: "10:30", from: at(13, 9, 0), to: at(13, 12, 0), want: at(13, 10, 30), ok: true},
+		{name: "up to the end", value: " 12:00 ", from: at(13, 9, 0), to: at(13, 12, 0), want: at(13, 12, 0), ok: true},
+		{name: "at the start", value: "09:00", from: at(13, 9, 0), to: at(13, 12, 0)},
+		{name: "after the end", value: "12:01", from: at(13, 9, 0), to: at(13, 12, 0)},
+		{name: "not a time", value: "half past ten", from: at(13, 9, 0), to: at(13, 12, 0)},
+		{name: "after midnight", value: "00:30", from: at(13, 23, 0), to: at(14, 1, 0), want: at(14, 0, 30), ok: true},
+		{name: "before midnight", value: "23:30", from: at(13, 23, 0), to: at(14, 1, 0), want: at(13, 23, 30), ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLogTime(tt.value, tt.from, tt.to)
+
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("got %s %v, want %s %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	timesheetDateFormat     = "yyyy-mm-dd"
+	timesheetTimeFormat     = "hh:mm"
+	timesheetDurationFormat = "[h]:mm"
+)
+
+var timesheetHeader = []string{"Дата", "Начало", "Конец", "Длительность", "Задача", "Проект", "Теги", "Ключ задачи"}
+
+type timesheetStyles struct {
+	header   int
+	date     int
+	time     int
+	duration int
+	total    int
+}
+
+func (a *ApiHandler) HandleExportXlsxCommand(userId int64, args string) {
+	from, to, err := parseDateRangeArg(args, time.Now())
+
+	if err != nil {
+		a.sendMessage(userId, fmt.Sprintf("Укажите период в формате ГГГГ-ММ-ДД, например: /%s 2024-05-01 2024-05-31 или /%s 2024-05-13. Без аргументов выгружается текущий месяц",
+			constants.ExportXlsxCommand, constants.ExportXlsxCommand))
+		return
+	}
+
+	days, err := a.getLogsByRange(userId, from, to)
+
+	if err != nil {
+		logrus.Errorf("Failed to get logs: %v", err)
+		return
+	}
+
+	if len(days) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("Логов за период %s - %s не найдено", utils.GetOnlyDate(from), utils.GetOnlyDate(to)))
+		return
+	}
+
+	data, err := buildTimesheet(days)
+
+	if err != nil {
+		logrus.Errorf("Failed to build timesheet: %v", err)
+		return
+	}
+
+	err = a.tgClient.SendDocument(&models.SendDocumentRequest{
+		ChatId:   userId,
+		FileName: fmt.Sprintf("timesheet_%s_%s.xlsx", utils.GetOnlyDate(from), utils.GetOnlyDate(to)),
+		Data:     data,
+		Caption:  fmt.Sprintf("Табель за период %s - %s", utils.GetOnlyDate(from), utils.GetOnlyDate(to)),
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send document: %v", err)
+	}
+}
+
+// buildTimesheet puts every ISO week on its own sheet. Durations and totals
+// are formulas, so edits made in the workbook are recalculated.
+func buildTimesheet(days []dayLogs) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newTimesheetStyles(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defaultSheet := f.GetSheetName(0)
+	sheetName := ""
+	row := 0
+
+	var dayTotalCells []string
+
+	for _, day := range days {
+		year, week := day.date.ISOWeek()
+		weekSheet := fmt.Sprintf("%d-W%02d", year, week)
+
+		if weekSheet != sheetName {
+			if sheetName != "" {
+				err = writeWeekTotal(f, sheetName, row+1, dayTotalCells, styles)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if sheetName == "" {
+				err = f.SetSheetName(defaultSheet, weekSheet)
+			} else {
+				_, err = f.NewSheet(weekSheet)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			err = writeTimesheetHeader(f, weekSheet, styles)
+
+			if err != nil {
+				return nil, err
+			}
+
+			sheetName = weekSheet
+			row = 1
+			dayTotalCells = nil
+		}
+
+		firstRow := row + 1
+
+		for _, v := range day.logs {
+			row++
+
+			err = writeTimesheetRow(f, sheetName, row, day.date, v, styles)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		row++
+		totalCell := fmt.Sprintf("D%d", row)
+		dayTotalCells = append(dayTotalCells, totalCell)
+
+		err = writeTotalRow(f, sheetName, row, fmt.Sprintf("Итого за %s", utils.GetOnlyDate(day.date)), fmt.Sprintf("SUM(D%d:D%d)", firstRow, row-1), styles)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writeWeekTotal(f, sheetName, row+1, dayTotalCells, styles)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.SetActiveSheet(0)
+
+	buf, err := f.WriteToBuffer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newTimesheetStyles(f *excelize.File) (*timesheetStyles, error) {
+	var err error
+	styles := &timesheetStyles{}
+	dateFormat, timeFormat, durationFormat := timesheetDateFormat, timesheetTimeFormat, timesheetDurationFormat
+
+	for _, v := range []struct {
+		id    *int
+		style *excelize.Style
+	}{
+		{&styles.header, &excelize.Style{Font: &excelize.Font{Bold: true}}},
+		{&styles.date, &excelize.Style{CustomNumFmt: &dateFormat}},
+		{&styles.time, &excelize.Style{CustomNumFmt: &timeFormat}},
+		{&styles.duration, &excelize.Style{CustomNumFmt: &durationFormat}},
+		{&styles.total, &excelize.Style{CustomNumFmt: &durationFormat, Font: &excelize.Font{Bold: true}}},
+	} {
+		*v.id, err = f.NewStyle(v.style)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return styles, nil
+}
+
+func writeTimesheetHeader(f *excelize.File, sheet string, styles *timesheetStyles) error {
+	for i, v := range timesheetHeader {
+		cell, err := excelize.CoordinatesToCellName(i+1, 1)
+
+		if err != nil {
+			return err
+		}
+
+		err = f.SetCellValue(sheet, cell, v)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err := f.SetCellStyle(sheet, "A1", "H1", styles.header)
+
+	if err != nil {
+		return err
+	}
+
+	err = f.SetColWidth(sheet, "A", "D", 14)
+
+	if err != nil {
+		return err
+	}
+
+	return f.SetColWidth(sheet, "E", "E", 50)
+}
+
+func writeTimesheetRow(f *excelize.File, sheet string, row int, date time.Time, log models.LogsInfoDto, styles *timesheetStyles) error {
+	values := []struct {
+		column string
+		value  any
+		style  int
+	}{
+		{"A", toExcelTime(date), styles.date},
+		{"B", toExcelTime(log.StartWorkTime), styles.time},
+		{"C", toExcelTime(log.EndWorkTime), styles.time},
+		{"E", log.Message, 0},
+		{"F", log.Project, 0},
+		{"G", strings.Join(log.Tags, ", "), 0},
+		{"H", log.IssueKey, 0},
+	}
+
+	for _, v := range values {
+		cell := fmt.Sprintf("%s%d", v.column, row)
+
+		err := f.SetCellValue(sheet, cell, v.value)
+
+		if err != nil {
+			return err
+		}
+
+		if v.style != 0 {
+			err = f.SetCellStyle(sheet, cell, cell, v.style)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cell := fmt.Sprintf("D%d", row)
+
+	err := f.SetCellFormula(sheet, cell, fmt.Sprintf("C%d-B%d", row, row))
+
+	if err != nil {
+		return err
+	}
+
+	return f.SetCellStyle(sheet, cell, cell, styles.duration)
+}
+
+func writeWeekTotal(f *excelize.File, sheet string, row int, dayTotalCells []string, styles *timesheetStyles) error {
+	return writeTotalRow(f, sheet, row, "Итого за неделю", fmt.Sprintf("SUM(%s)", strings.Join(dayTotalCells, ",")), styles)
+}
+
+func writeTotalRow(f *excelize.File, sheet string, row int, title string, formula string, styles *timesheetStyles) error {
+	titleCell := fmt.Sprintf("A%d", row)
+	totalCell := fmt.Sprintf("D%d", row)
+
+	err := f.SetCellValue(sheet, titleCell, title)
+
+	if err != nil {
+		return err
+	}
+
+	err = f.SetCellStyle(sheet, titleCell, titleCell, styles.header)
+
+	if err != nil {
+		return err
+	}
+
+	err = f.SetCellFormula(sheet, totalCell, formula)
+
+	if err != nil {
+		return err
+	}
+
+	return f.SetCellStyle(sheet, totalCell, totalCell, styles.total)
+}
+
+// toExcelTime keeps the wall clock of the time, spreadsheets have no time zones.
+func toExcelTime(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, time.UTC)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"bytes"
+	"logs-aggregator-bot/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestBuildTimesheet(t *testing.T) {
+	newDay := func(day int, messages ...string) dayLogs {
+		date := time.Date(2024, 5, day, 0, 0, 0, 0, time.Local)
+		result := dayLogs{date: date}
+
+		for i, v := range messages {
+			start := date.Add(time.Duration(9+i) * time.Hour)
+			result.logs = append(result.logs, models.LogsInfoDto{StartWorkTime: start, EndWorkTime: start.Add(45 * time.Minute), Message: v, Project: "bot", Tags: []string{"a", "b"}, IssueKey: "PROJ-1"})
+		}
+
+		return result
+	}
+
+	data, err := buildTimesheet([]dayLogs{newDay(16, "first", "second"), newDay(17, "third"), newDay(20, "fourth")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	if got, want := f.GetSheetList(), []string{"2024-W20", "2024-W21"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got sheets %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		sheet   string
+		cell    string
+		value   string
+		formula string
+	}{
+		{sheet: "2024-W20", cell: "A1", value: "Дата"},
+		{sheet: "2024-W20", cell: "A2", value: "2024-05-16"},
+		{sheet: "2024-W20", cell: "B2", value: "09:00"},
+		{sheet: "2024-W20", cell: "C3", value: "10:45"},
+		{sheet: "2024-W20", cell: "D2", formula: "C2-B2"},
+		{sheet: "2024-W20", cell: "E3", value: "second"},
+		{sheet: "2024-W20", cell: "G2", value: "a, b"},
+		{sheet: "2024-W20", cell: "H2", value: "PROJ-1"},
+		{sheet: "2024-W20", cell: "A4", value: "Итого за 2024-05-16"},
+		{sheet: "2024-W20", cell: "D4", formula: "SUM(D2:D3)"},
+		{sheet: "2024-W20", cell: "E5", value: "third"},
+		{sheet: "2024-W20", cell: "D6", formula: "SUM(D5:D5)"},
+		{sheet: "2024-W20", cell: "A7", value: "Итого за неделю"},
+		{sheet: "2024-W20", cell: "D7", formula: "SUM(D4,D6)"},
+		{sheet: "2024-W21", cell: "A2", value: "2024-05-20"},
+		{sheet: "2024-W21", cell: "D4", formula: "SUM(D3)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sheet+"!"+tt.cell, func(t *testing.T) {
+			if tt.formula != "" {
+				formula, err := f.GetCellFormula(tt.sheet, tt.cell)
+
+				if err != nil || formula != tt.formula {
+					t.Errorf("got formula %q (%v), want %q", formula, err, tt.formula)
+				}
+
+				return
+			}
+
+			value, err := f.GetCellValue(tt.sheet, tt.cell)
+
+			if err != nil || value != tt.value {
+				t.Errorf("got %q (%v), want %q", value, err, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/utils"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// trashRetention is how long deleted days can be restored.
+	trashRetention     = 30 * 24 * time.Hour
+	trashPurgeInterval = time.Hour
+)
+
+func (a *ApiHandler) HandleTrashCommand(userId int64) {
+	days, err := a.provider.GetTrashedDays(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get trashed days: %v", err)
+		return
+	}
+
+	if len(days) == 0 {
+		a.sendMessage(userId, "Корзина пуста")
+		return
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("Корзина:\n")
+
+	for _, v := range days {
+		sb.WriteString(fmt.Sprintf("%s: записей %d, удалено %s, хранится до %s\n",
+			v.Date, v.LogsCount, utils.GetOnlyDate(v.TrashedAt), utils.GetOnlyDate(v.TrashedAt.Add(trashRetention))))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nВернуть день: /%s ГГГГ-ММ-ДД", constants.RestoreCommand))
+
+	a.sendMessage(userId, sb.String())
+}
+
+func (a *ApiHandler) HandleRestoreCommand(userId int64, args string) {
+	if date := strings.TrimSpace(args); date != "" {
+		_, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+
+		if err != nil {
+			a.sendMessage(userId, fmt.Sprintf("Укажите дату в формате ГГГГ-ММ-ДД, например: /%s 2024-05-13", constants.RestoreCommand))
+			return
+		}
+
+		a.restoreDay(userId, date)
+		return
+	}
+
+	days, err := a.provider.GetTrashedDays(userId)
+
+	if err != nil {
+		logrus.Errorf("Failed to get trashed days: %v", err)
+		return
+	}
+
+	if len(days) == 0 {
+		a.sendMessage(userId, "Корзина пуста")
+		return
+	}
+
+	_, err = a.setUserState(userId, constants.UserStateSelectRestoreDate)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	var markup []models.MarkupData
+
+	for _, v := range days {
+		markup = append(markup, models.MarkupData{
+			Key:   fmt.Sprintf("%s (записей: %d)", v.Date, v.LogsCount),
+			Value: v.Date,
+		})
+	}
+
+	err = a.tgClient.SendMessage(&models.SendNotificationRequest{
+		ChatId:   userId,
+		Body:     "Выберите день, который хотите вернуть из корзины",
+		Markup:   markup,
+		Controls: []models.MarkupData{{Key: "Отмена", Value: constants.CallbackParamCancel}},
+		Columns:  2,
+	})
+
+	if err != nil {
+		logrus.Errorf("Failed to send message: %v", err)
+	}
+}
+
+func (a *ApiHandler) HandleCallbackSelectRestoreDate(userId int64, data string) {
+	_, err := a.setUserState(userId, constants.UserStateNone)
+
+	if err != nil {
+		logrus.Errorf("Failed to set user settings: %v", err)
+		return
+	}
+
+	if data == constants.CallbackParamCancel {
+		return
+	}
+
+	a.restoreDay(userId, data)
+}
+
+func (a *ApiHandler) restoreDay(userId int64, date string) {
+	logs, err := a.RestoreLogsByDate(userId, date)
+
+	if err != nil {
+		logrus.Errorf("Failed to restore logs: %v", err)
+		return
+	}
+
+	if len(logs) == 0 {
+		a.sendMessage(userId, fmt.Sprintf("В корзине нет логов за %s", date))
+		return
+	}
+
+	a.sendMessage(userId, fmt.Sprintf("Логи за %s восстановлены, записей: %d", date, len(logs)))
+}
+
+// RunTrashPurge removes the days kept in the trash longer than trashRetention.
+func (a *ApiHandler) RunTrashPurge(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		err := a.provider.PurgeTrash(time.Now().Add(-trashRetention))
+
+		if err != nil {
+			logrus.Errorf("Failed to purge trash: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"logs-aggregator-bot/jira"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/provider"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const trashTestUserId = 1
+
+func newTrashTestStorage(t *testing.T) provider.StorageProvider {
+	storage, err := provider.NewSqliteStorageProvider(filepath.Join(t.TempDir(), provider.DefaultSqliteFile))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = storage.Close()
+	})
+
+	err = storage.SetUserSettings(&models.UserSettingsDto{UserId: trashTestUserId, JiraToken: "pat"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return storage
+}
+
+func getTrashTestRequests(standIn *jiraStandIn, count int) string {
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		standIn.mu.Lock()
+		requests := fmt.Sprint(standIn.requests)
+		done := len(standIn.requests) >= count
+		standIn.mu.Unlock()
+
+		if done || time.Now().After(deadline) {
+			return requests
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTrashAndRestoreDay(t *testing.T) {
+	storage := newTrashTestStorage(t)
+
+	// The worklog is deleted with the trashed day, so the update on restore
+	// finds nothing and the worklog is added again.
+	standIn := &jiraStandIn{statuses: []int{http.StatusNoContent, http.StatusNotFound}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	tgCli := &fakeTgClient{}
+	jiraService := NewJiraSyncService(storage, jira.NewClient(server.URL, nil), tgCli)
+	jiraService.retryDelay = 0
+	handler := NewApiHandler(storage, tgCli, nil, jiraService, nil, "")
+
+	log := newJiraTestLog("7")
+	log.JiraIssueKey = log.IssueKey
+
+	err := storage.InsertNewLogRecord(trashTestUserId, log.EndWorkTime, &log)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = handler.TrashLogsByDate(trashTestUserId, "2024-05-13")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests := getTrashTestRequests(standIn, 1); requests != "[DELETE /rest/api/2/issue/PROJ-1/worklog/7]" {
+		t.Errorf("got requests %s after trashing", requests)
+	}
+
+	dates, err := storage.GetDatesWithLogs(trashTestUserId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dates) != 0 {
+		t.Errorf("got dates %v after trashing", dates)
+	}
+
+	restored, err := handler.RestoreLogsByDate(trashTestUserId, "2024-05-13")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(restored) != 1 || restored[0].Id != log.Id {
+		t.Fatalf("got restored logs %+v", restored)
+	}
+
+	want := "[DELETE /rest/api/2/issue/PROJ-1/worklog/7 PUT /rest/api/2/issue/PROJ-1/worklog/7 POST /rest/api/2/issue/PROJ-1/worklog]"
+
+	if requests := getTrashTestRequests(standIn, 3); requests != want {
+		t.Errorf("got requests %s after the restore, want %s", requests, want)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		logs, err := storage.GetLogRecords(trashTestUserId, log.EndWorkTime)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(logs) == 1 && logs[0].JiraWorklogId == "3" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got logs %+v after the restore, want the new worklog id", logs)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunTrashPurge(t *testing.T) {
+	storage := newTrashTestStorage(t)
+	handler := NewApiHandler(storage, &fakeTgClient{}, nil, nil, nil, "")
+
+	days := map[string]time.Duration{
+		"2024-05-13": trashRetention + time.Hour,
+		"2024-05-14": trashRetention - time.Hour,
+	}
+
+	for date, age := range days {
+		start, _ := time.ParseInLocation(time.DateOnly, date, time.Local)
+		log := models.LogsInfoDto{Id: date, StartWorkTime: start.Add(9 * time.Hour), EndWorkTime: start.Add(10 * time.Hour)}
+
+		err := storage.InsertNewLogRecord(trashTestUserId, log.EndWorkTime, &log)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = storage.TrashLogsByDate(trashTestUserId, date, time.Now().Add(-age))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The purge runs once before waiting for the ticker.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler.RunTrashPurge(ctx)
+
+	trashed, err := storage.GetTrashedDays(trashTestUserId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trashed) != 1 || trashed[0].Date != "2024-05-14" {
+		t.Errorf("got trashed days %+v after the purge", trashed)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"logs-aggregator-bot/models"
+	"logs-aggregator-bot/provider"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookPollPeriod   = 30 * time.Second
+	webhookBatchSize    = 50
+	webhookMaxAttempts  = 10
+	webhookRetryDelay   = 30 * time.Second
+	webhookMaxRetryWait = time.Hour
+)
+
+type webhookClient interface {
+	Deliver(ctx context.Context, url string, event string, deliveryId string, body []byte) error
+}
+
+type WebhookService struct {
+	provider provider.StorageProvider
+	client   webhookClient
+	urls     []string
+	wake     chan struct{}
+}
+
+func NewWebhookService(provider provider.StorageProvider, client webhookClient, urls []string) *WebhookService {
+	return &WebhookService{provider: provider, client: client, urls: urls, wake: make(chan struct{}, 1)}
+}
+
+// Publish stores the event in the delivery queue of every configured url, a
+// nil service stands for disabled webhooks.
+func (w *WebhookService) Publish(event models.WebhookEventDto) {
+	if w == nil {
+		return
+	}
+
+	event.Id = uuid.NewString()
+	event.CreatedAt = time.Now()
+
+	payload, err := json.Marshal(event)
+
+	if err != nil {
+		logrus.Errorf("Failed to marshal webhook event: %v", err)
+		return
+	}
+
+	var deliveries []*models.WebhookDeliveryDto
+
+	for _, url := range w.urls {
+		deliveries = append(deliveries, &models.WebhookDeliveryDto{
+			Id:            uuid.NewString(),
+			EventType:     event.Type,
+			Url:           url,
+			Payload:       string(payload),
+			NextAttemptAt: event.CreatedAt,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+
+	err = w.provider.EnqueueWebhookDeliveries(deliveries)
+
+	if err != nil {
+		logrus.Errorf("Failed to enqueue webhook event %s: %v", event.Type, err)
+		return
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers queued events until the context is done. Deliveries left by a
+// previous run are picked up on start.
+func (w *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollPeriod)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *WebhookService) deliverDue(ctx context.Context) {
+	deliveries, err := w.provider.GetDueWebhookDeliveries(time.Now(), webhookBatchSize)
+
+	if err != nil {
+		logrus.Errorf("Failed to get webhook deliveries: %v", err)
+		return
+	}
+
+	for _, v := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		w.deliver(ctx, v)
+	}
+}
+
+func (w *WebhookService) deliver(ctx context.Context, delivery models.WebhookDeliveryDto) {
+	err := w.client.Deliver(ctx, delivery.Url, string(delivery.EventType), delivery.Id, []byte(delivery.Payload))
+
+	if err == nil || delivery.Attempts+1 >= webhookMaxAttempts {
+		if err != nil {
+			logrus.Errorf("Drop webhook delivery %s to %s after %d attempts: %v", delivery.Id, delivery.Url, webhookMaxAttempts, err)
+		}
+
+		err = w.provider.DeleteWebhookDelivery(delivery.Id)
+
+		if err != nil {
+			logrus.Errorf("Failed to delete webhook delivery: %v", err)
+		}
+
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+
+	logrus.Warnf("Webhook delivery %s to %s failed, retry at %s: %v", delivery.Id, delivery.Url, delivery.NextAttemptAt.Format(time.DateTime), err)
+
+	err = w.provider.UpdateWebhookDelivery(&delivery)
+
+	if err != nil {
+		logrus.Errorf("Failed to update webhook delivery: %v", err)
+	}
+}
+
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryDelay
+
+	for i := 1; i < attempts && delay < webhookMaxRetryWait; i++ {
+		delay *= 2
+	}
+
+	return min(delay, webhookMaxRetryWait)
+}
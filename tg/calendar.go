@@ -0,0 +1,154 @@
+package tg
+
+import (
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const calendarMonthFormat = "2006-01"
+
+var (
+	calendarMonthNames = []string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+	calendarDayNames   = []string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+)
+
+// calendarKeyboard is a month grid starting on Monday. Marked days get a dot,
+// with OnlyMarked the month navigation stops at the first and the last mark.
+type calendarKeyboard struct {
+	month      time.Time
+	marked     map[string]bool
+	firstMark  string
+	lastMark   string
+	onlyMarked bool
+	controls   []models.MarkupData
+}
+
+func newCalendarKeyboard(req *models.SendNotificationRequest) *calendarKeyboard {
+	month := req.Calendar.Month
+
+	if month.IsZero() {
+		month = time.Now()
+	}
+
+	dates := append([]string(nil), req.Calendar.MarkedDates...)
+	sort.Strings(dates)
+
+	keyboard := &calendarKeyboard{
+		month:      time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local),
+		marked:     map[string]bool{},
+		onlyMarked: req.Calendar.OnlyMarked,
+		controls:   req.Controls,
+	}
+
+	for _, v := range dates {
+		keyboard.marked[v] = true
+	}
+
+	if len(dates) > 0 {
+		keyboard.firstMark = dates[0]
+		keyboard.lastMark = dates[len(dates)-1]
+	}
+
+	return keyboard
+}
+
+func (k *calendarKeyboard) isNavigable() bool {
+	return true
+}
+
+func (k *calendarKeyboard) navigate(data string) (bool, error) {
+	month, err := time.ParseInLocation(calendarMonthFormat, strings.TrimPrefix(data, constants.CallbackPrefixCalendar), time.Local)
+
+	if err != nil {
+		return false, err
+	}
+
+	if month.Equal(k.month) {
+		return false, nil
+	}
+
+	k.month = month
+	return true, nil
+}
+
+func (k *calendarKeyboard) render() tgbotapi.InlineKeyboardMarkup {
+	markup := tgbotapi.NewInlineKeyboardMarkup(k.renderNavigation())
+
+	var header []tgbotapi.InlineKeyboardButton
+
+	for _, v := range calendarDayNames {
+		header = append(header, ignoredButton(v))
+	}
+
+	markup.InlineKeyboard = append(markup.InlineKeyboard, header)
+
+	var week []tgbotapi.InlineKeyboardButton
+
+	for i := 0; i < (int(k.month.Weekday())+6)%7; i++ {
+		week = append(week, ignoredButton(" "))
+	}
+
+	for day := k.month; day.Month() == k.month.Month(); day = day.AddDate(0, 0, 1) {
+		week = append(week, k.renderDay(day))
+
+		if len(week) == len(calendarDayNames) {
+			markup.InlineKeyboard = append(markup.InlineKeyboard, week)
+			week = nil
+		}
+	}
+
+	if len(week) > 0 {
+		for len(week) < len(calendarDayNames) {
+			week = append(week, ignoredButton(" "))
+		}
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, week)
+	}
+
+	return appendControls(markup, k.controls)
+}
+
+func (k *calendarKeyboard) renderNavigation() []tgbotapi.InlineKeyboardButton {
+	prevMonth := k.month.AddDate(0, -1, 0)
+	nextMonth := k.month.AddDate(0, 1, 0)
+	title := fmt.Sprintf("%s %d", calendarMonthNames[k.month.Month()-1], k.month.Year())
+
+	var row []tgbotapi.InlineKeyboardButton
+
+	if !k.onlyMarked || (k.firstMark != "" && k.firstMark < k.month.Format(time.DateOnly)) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("«", constants.CallbackPrefixCalendar+prevMonth.Format(calendarMonthFormat)))
+	}
+
+	row = append(row, ignoredButton(title))
+
+	if !k.onlyMarked || k.lastMark >= nextMonth.Format(time.DateOnly) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("»", constants.CallbackPrefixCalendar+nextMonth.Format(calendarMonthFormat)))
+	}
+
+	return row
+}
+
+func (k *calendarKeyboard) renderDay(day time.Time) tgbotapi.InlineKeyboardButton {
+	date := day.Format(time.DateOnly)
+	title := fmt.Sprint(day.Day())
+
+	if k.marked[date] {
+		title = "•" + title
+	}
+
+	if k.onlyMarked && !k.marked[date] {
+		return ignoredButton(title)
+	}
+
+	return tgbotapi.NewInlineKeyboardButtonData(title, date)
+}
+
+func ignoredButton(title string) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData(title, constants.CallbackParamIgnore)
+}
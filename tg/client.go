@@ -7,25 +7,70 @@ import (
 )
 
 type TgClient struct {
-	bot *tgbotapi.BotAPI
+	bot       *tgbotapi.BotAPI
+	keyboards *keyboardStore
 }
 
 func NewTgClient(bot *tgbotapi.BotAPI) *TgClient {
-	return &TgClient{bot: bot}
+	return &TgClient{bot: bot, keyboards: newKeyboardStore()}
 }
 
 func (t *TgClient) SendMessage(req *models.SendNotificationRequest) error {
 	msg := tgbotapi.NewMessage(req.ChatId, req.Body)
+	keyboard := newKeyboard(req)
 
-	if len(req.Markup) > 0 {
-		markup := tgbotapi.NewInlineKeyboardMarkup()
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard.render()
+	}
+
+	sent, err := t.bot.Send(msg)
+
+	if err != nil {
+		return err
+	}
+
+	if keyboard != nil && keyboard.isNavigable() {
+		t.keyboards.add(req.ChatId, sent.MessageID, keyboard)
+	}
+
+	return nil
+}
+
+// NavigateKeyboard redraws the keyboard of the message after one of its
+// navigation buttons is pressed.
+func (t *TgClient) NavigateKeyboard(chatId int64, messageId int, data string) error {
+	keyboard, changed, err := t.keyboards.navigate(chatId, messageId, data)
+
+	if err != nil || !changed {
+		return err
+	}
 
-		for _, v := range req.Markup {
-			markup.InlineKeyboard = append(markup.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(v.Key, v.Value)))
-		}
-		msg.ReplyMarkup = markup
+	_, err = t.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatId, messageId, keyboard.render()))
+	return err
+}
+
+func newKeyboard(req *models.SendNotificationRequest) inlineKeyboard {
+	if req.Calendar != nil {
+		return newCalendarKeyboard(req)
 	}
 
-	_, err := t.bot.Send(msg)
+	if req.TimePicker != nil {
+		return newTimePickerKeyboard(req)
+	}
+
+	keyboard := newPagedKeyboard(req)
+
+	if keyboard.isEmpty() {
+		return nil
+	}
+
+	return keyboard
+}
+
+func (t *TgClient) SendDocument(req *models.SendDocumentRequest) error {
+	doc := tgbotapi.NewDocumentUpload(req.ChatId, tgbotapi.FileBytes{Name: req.FileName, Bytes: req.Data})
+	doc.Caption = req.Caption
+
+	_, err := t.bot.Send(doc)
 	return err
 }
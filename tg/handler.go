@@ -2,25 +2,40 @@ package tg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
 	"logs-aggregator-bot/provider"
 	"logs-aggregator-bot/services"
+	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+const (
+	chatQueueSize = 100
+	// chatWorkerIdleTimeout stops the worker of a chat that sent nothing for a
+	// while, the next update starts a new one.
+	chatWorkerIdleTimeout = 10 * time.Minute
+)
+
 type TgHandler struct {
 	updates  <-chan tgbotapi.Update
 	bot      *tgbotapi.BotAPI
+	client   *TgClient
 	handler  *services.ApiHandler
-	provider *provider.JsonStorageProvider
+	provider provider.UserSettingsStorage
+	mu       sync.Mutex
+	queues   map[int64]chan tgbotapi.Update
 }
 
-func NewTgHandler(bot *tgbotapi.BotAPI, handler *services.ApiHandler, provider *provider.JsonStorageProvider) *TgHandler {
+func NewTgHandler(bot *tgbotapi.BotAPI, client *TgClient, handler *services.ApiHandler, provider provider.UserSettingsStorage) *TgHandler {
 	updates, _ := bot.GetUpdatesChan(tgbotapi.NewUpdate(0))
-	return &TgHandler{bot: bot, handler: handler, provider: provider, updates: updates}
+	return &TgHandler{bot: bot, client: client, handler: handler, provider: provider, updates: updates, queues: map[int64]chan tgbotapi.Update{}}
 }
 
 func (t *TgHandler) Start(ctx context.Context) {
@@ -30,48 +45,208 @@ func (t *TgHandler) Start(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case update := <-t.updates:
-			if update.CallbackQuery != nil {
-				go t.processCallback(update)
+			chatId, ok := getChatId(update)
+
+			if !ok {
 				continue
 			}
-			if update.Message != nil {
-				if update.Message.IsCommand() {
-					go t.processCommand(update)
-					continue
-				}
-				go t.processMessage(update)
+
+			t.enqueue(ctx, chatId, update)
+		}
+	}
+}
+
+func (t *TgHandler) enqueue(ctx context.Context, chatId int64, update tgbotapi.Update) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	queue, exist := t.queues[chatId]
+
+	if !exist {
+		queue = make(chan tgbotapi.Update, chatQueueSize)
+		t.queues[chatId] = queue
+
+		go t.runChatWorker(ctx, chatId, queue)
+	}
+
+	select {
+	case queue <- update:
+	default:
+		logrus.Warnf("Update queue for chat %d is full, drop update %d", chatId, update.UpdateID)
+	}
+}
+
+func (t *TgHandler) runChatWorker(ctx context.Context, chatId int64, queue <-chan tgbotapi.Update) {
+	timer := time.NewTimer(chatWorkerIdleTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-queue:
+			t.processUpdate(update)
+			timer.Reset(chatWorkerIdleTimeout)
+		case <-timer.C:
+			if t.removeIdleQueue(chatId, queue) {
+				return
 			}
+
+			timer.Reset(chatWorkerIdleTimeout)
 		}
 	}
 }
 
-func (t *TgHandler) processCallback(update tgbotapi.Update) {
-	settings, err := t.provider.GetUserSettings()
+// removeIdleQueue drops the queue of the chat unless an update was enqueued
+// meanwhile, the worker stops once it is dropped.
+func (t *TgHandler) removeIdleQueue(chatId int64, queue <-chan tgbotapi.Update) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(queue) > 0 {
+		return false
+	}
+
+	delete(t.queues, chatId)
+	return true
+}
+
+func (t *TgHandler) processUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		t.processCallback(update)
+		return
+	}
+
+	if update.Message.IsCommand() {
+		t.processCommand(update)
+		return
+	}
+
+	t.processMessage(update)
+}
+
+func (t *TgHandler) getGrantedUser(chatId int64) (*models.UserSettingsDto, bool) {
+	settings, err := t.provider.GetUserSettings(chatId)
+
+	if errors.Is(err, provider.ErrUserNotFound) {
+		logrus.Errorf("Not granted user %d, skip", chatId)
+		return nil, false
+	}
 
 	if err != nil {
 		logrus.Errorf("Failed to get user settings: %s", err.Error())
+		return nil, false
+	}
+
+	if !settings.IsActive() {
+		logrus.Warnf("User %d is %s, skip", chatId, settings.Status)
+		return nil, false
+	}
+
+	return settings, true
+}
+
+func getChatId(update tgbotapi.Update) (int64, bool) {
+	if update.CallbackQuery != nil && update.CallbackQuery.Message != nil {
+		return update.CallbackQuery.Message.Chat.ID, true
+	}
+
+	if update.Message != nil {
+		return update.Message.Chat.ID, true
+	}
+
+	return 0, false
+}
+
+func (t *TgHandler) processCallback(update tgbotapi.Update) {
+	chatId := update.CallbackQuery.Message.Chat.ID
+	settings, ok := t.getGrantedUser(chatId)
+
+	if !ok {
 		return
 	}
 
-	if settings.UserId != update.CallbackQuery.Message.Chat.ID {
-		logrus.Errorf("Not granted user callback, skip")
+	if update.CallbackQuery.Data == constants.CallbackParamIgnore || isNavigationCallback(update.CallbackQuery.Data) {
+		var err error
+		answer := ""
+
+		if update.CallbackQuery.Data != constants.CallbackParamIgnore {
+			err = t.client.NavigateKeyboard(chatId, update.CallbackQuery.Message.MessageID, update.CallbackQuery.Data)
+		}
+
+		if errors.Is(err, ErrKeyboardExpired) {
+			answer = "Список устарел, повторите команду"
+		} else if err != nil {
+			logrus.Errorf("Failed to navigate keyboard: %v", err)
+		}
+
+		_, err = t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, answer))
+
+		if err != nil {
+			logrus.Errorf("Failed to answer callback: %s", err.Error())
+		}
+
+		return
+	}
+
+	if strings.HasPrefix(update.CallbackQuery.Data, constants.CallbackPrefixUndoDelete) {
+		t.handler.HandleCallbackUndoDeleteLog(chatId, update.CallbackQuery.Data)
+
+		_, err := t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Запрос обработан успешно"))
+
+		if err != nil {
+			logrus.Errorf("Failed asnwer callback: %v", err)
+		}
+
 		return
 	}
 
 	switch settings.CurrentState {
 	case constants.UserStateSelectLogType:
-		t.handler.HandleCallbackSelectLogType(update.CallbackQuery.Data)
+		t.handler.HandleCallbackSelectLogType(chatId, update.CallbackQuery.Data)
 	case constants.UserStateSelectNewLogDate:
-		t.handler.HandleCallbackSelectNewLogDate(update.CallbackQuery.Data)
+		t.handler.HandleCallbackSelectNewLogDate(chatId, update.CallbackQuery.Data)
 	case constants.UserStateSelectOldLogDate:
-		t.handler.HandleCallbackSelectOldLogDate(update.CallbackQuery.Data)
+		t.handler.HandleCallbackSelectOldLogDate(chatId, update.CallbackQuery.Data)
 	case constants.UserStateSelectLogDate:
-		t.handler.HandleCallbackWithGetLog(update.CallbackQuery.Data)
+		t.handler.HandleCallbackWithGetLog(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectIssueSuggestion:
+		t.handler.HandleCallbackSelectIssueSuggestion(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectProject:
+		t.handler.HandleCallbackSelectProject(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectEditLog:
+		t.handler.HandleCallbackSelectEditLog(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectEditField:
+		t.handler.HandleCallbackSelectEditField(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectDeleteLog:
+		t.handler.HandleCallbackSelectDeleteLog(chatId, update.CallbackQuery.Data)
+	case constants.UserStateConfirmDeleteLog:
+		t.handler.HandleCallbackConfirmDeleteLog(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectTags:
+		answer := t.handler.HandleCallbackSelectTags(chatId, update.CallbackQuery.Data)
+
+		_, err := t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, answer))
+
+		if err != nil {
+			logrus.Errorf("Failed to answer callback: %s", err.Error())
+		}
+
+		return
 	case constants.UserStateSelectLogsToDelete:
-		isDeleted := t.handler.HandleDeleteCallbackParam(update.CallbackQuery.Data)
+		t.handler.HandleDeleteCallbackParam(chatId, update.CallbackQuery.Data)
+	case constants.UserStateConfirmQuickLog:
+		t.handler.HandleCallbackConfirmQuickLog(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectReportFrom:
+		t.handler.HandleCallbackSelectReportFrom(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectReportTo:
+		t.handler.HandleCallbackSelectReportTo(chatId, update.CallbackQuery.Data)
+	case constants.UserStateSelectRestoreDate:
+		t.handler.HandleCallbackSelectRestoreDate(chatId, update.CallbackQuery.Data)
+	case constants.UserStateConfirmDeleteLogs:
+		isDeleted := t.handler.HandleCallbackConfirmDeleteLogs(chatId, update.CallbackQuery.Data)
 
 		if isDeleted {
-			_, err = t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Успешное удаление"))
+			_, err := t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Успешное удаление"))
 
 			if err != nil {
 				logrus.Errorf("Failed to answer callback: %s", err.Error())
@@ -79,7 +254,7 @@ func (t *TgHandler) processCallback(update tgbotapi.Update) {
 
 			return
 		} else {
-			_, err = t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Вы отменили операцию удаления"))
+			_, err := t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Вы отменили операцию удаления"))
 
 			if err != nil {
 				logrus.Errorf("Failed to answer callback: %s", err.Error())
@@ -91,7 +266,7 @@ func (t *TgHandler) processCallback(update tgbotapi.Update) {
 		return
 	}
 
-	_, err = t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Запрос обработан успешно"))
+	_, err := t.bot.AnswerCallbackQuery(tgbotapi.NewCallback(update.CallbackQuery.ID, "Запрос обработан успешно"))
 
 	if err != nil {
 		logrus.Errorf("Failed asnwer callback: %v", err)
@@ -99,53 +274,180 @@ func (t *TgHandler) processCallback(update tgbotapi.Update) {
 }
 
 func (t *TgHandler) processMessage(update tgbotapi.Update) {
-	settings, err := t.provider.GetUserSettings()
+	chatId := update.Message.Chat.ID
+	settings, ok := t.getGrantedUser(chatId)
 
-	if err != nil {
-		logrus.Errorf("Failed to get user settings: %s", err.Error())
+	if !ok {
 		return
 	}
 
-	if settings.UserId != update.Message.Chat.ID {
-		logrus.Errorf("Not granted user callback, skip")
-		return
+	if settings.CurrentState == constants.UserStateNone {
+		t.handler.HandleQuickLogMessage(chatId, update.Message.Text)
 	}
 
 	if settings.CurrentState == constants.UserStateSelectNewLogMessage {
-		t.handler.HandleSelectNewLogMessage(update.Message.Text)
+		t.handler.HandleSelectNewLogMessage(chatId, update.Message.Text)
+	}
+
+	if settings.CurrentState == constants.UserStateSelectNewLogDate || settings.CurrentState == constants.UserStateSelectOldLogDate {
+		t.handler.HandleLogTimeValue(chatId, update.Message.Text)
+	}
+
+	if settings.CurrentState == constants.UserStateEnterEditValue {
+		t.handler.HandleEditLogValue(chatId, update.Message.Text)
 	}
 }
 
 func (t *TgHandler) processCommand(update tgbotapi.Update) {
-	settings, err := t.provider.GetUserSettings()
+	chatId := update.Message.Chat.ID
 
-	if err != nil {
-		logrus.Errorf("Failed to get user settings: %s", err.Error())
+	if update.Message.Command() == string(constants.StartCommand) {
+		userName := ""
+
+		if update.Message.From != nil {
+			userName = update.Message.From.UserName
+		}
+
+		t.handler.HandleStartCommand(chatId, userName, update.Message.CommandArguments())
 		return
 	}
 
-	if settings.UserId != update.Message.Chat.ID {
-		logrus.Errorf("Not granted user callback, skip")
+	settings, ok := t.getGrantedUser(chatId)
+
+	if !ok {
 		return
 	}
 
+	if settings.IsAdmin() {
+		t.processAdminCommand(chatId, update)
+	}
+
 	if update.Message.Command() == string(constants.StartWorkDayCommand) {
-		t.handler.HandleStartWorkDayCommand()
+		t.handler.HandleStartWorkDayCommand(chatId)
 	}
 
 	if update.Message.Command() == string(constants.EndWorkDayCommand) {
-		t.handler.HandleStopWorkDayCommand()
+		t.handler.HandleStopWorkDayCommand(chatId)
 	}
 
 	if update.Message.Command() == string(constants.GetLogsCommand) {
-		t.handler.HandleGetLogsCommand()
+		t.handler.HandleGetLogsCommand(chatId)
 	}
 
 	if update.Message.Command() == string(constants.GetAllLogsCommand) {
-		t.handler.HandleGetAllLogsCommand()
+		t.handler.HandleGetAllLogsCommand(chatId)
 	}
 
 	if update.Message.Command() == string(constants.DeleteLogsCommand) {
-		t.handler.HandleDeleteLogsCommand()
+		t.handler.HandleDeleteLogsCommand(chatId)
+	}
+
+	if update.Message.Command() == string(constants.TrashCommand) {
+		t.handler.HandleTrashCommand(chatId)
+	}
+
+	if update.Message.Command() == string(constants.RestoreCommand) {
+		t.handler.HandleRestoreCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.EditLogCommand) {
+		t.handler.HandleEditLogCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.DeleteLogCommand) {
+		t.handler.HandleDeleteLogCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ProjectsCommand) {
+		t.handler.HandleProjectsCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.TagsCommand) {
+		t.handler.HandleTagsCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.IssuePatternsCommand) {
+		t.handler.HandleIssuePatternsCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.JiraAuthCommand) {
+		t.handler.HandleJiraAuthCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.JiraSyncCommand) {
+		t.handler.HandleJiraSyncCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ApiTokenCommand) {
+		t.handler.HandleApiTokenCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ExportCommand) {
+		t.handler.HandleExportCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ExportXlsxCommand) {
+		t.handler.HandleExportXlsxCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SettingsCommand) {
+		t.handler.HandleSettingsCommand(chatId)
+	}
+
+	if update.Message.Command() == string(constants.SetIntervalCommand) {
+		t.handler.HandleSetIntervalCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetCronCommand) {
+		t.handler.HandleSetCronCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetAutoStopCommand) {
+		t.handler.HandleSetAutoStopCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetWorkHoursCommand) {
+		t.handler.HandleSetWorkHoursCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetWorkDaysCommand) {
+		t.handler.HandleSetWorkDaysCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetTargetCommand) {
+		t.handler.HandleSetTargetCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetTimeStepCommand) {
+		t.handler.HandleSetTimeStepCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ReportWeekCommand) {
+		t.handler.HandleReportWeekCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ReportMonthCommand) {
+		t.handler.HandleReportMonthCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.ReportCommand) {
+		t.handler.HandleReportCommand(chatId, update.Message.CommandArguments())
+	}
+
+	if update.Message.Command() == string(constants.SetLunchCommand) {
+		t.handler.HandleSetLunchCommand(chatId, update.Message.CommandArguments())
+	}
+}
+
+func (t *TgHandler) processAdminCommand(chatId int64, update tgbotapi.Update) {
+	switch update.Message.Command() {
+	case string(constants.InviteCommand):
+		t.handler.HandleInviteCommand(chatId)
+	case string(constants.ApproveUserCommand):
+		t.handler.HandleApproveUserCommand(chatId, update.Message.CommandArguments())
+	case string(constants.BlockUserCommand):
+		t.handler.HandleBlockUserCommand(chatId, update.Message.CommandArguments())
+	case string(constants.ListUsersCommand):
+		t.handler.HandleListUsersCommand(chatId)
 	}
 }
@@ -0,0 +1,192 @@
+package tg
+
+import (
+	"errors"
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	keyboardPageRows = 8
+	// maxKeyboardsPerChat bounds the memory kept for old messages, paging an
+	// older keyboard reports it as expired.
+	maxKeyboardsPerChat = 20
+)
+
+var ErrKeyboardExpired = errors.New("keyboard expired")
+
+// inlineKeyboard is a keyboard that can redraw itself after one of its
+// navigation buttons is pressed.
+type inlineKeyboard interface {
+	render() tgbotapi.InlineKeyboardMarkup
+	// navigate applies the navigation button data, false when the keyboard
+	// already shows the requested view.
+	navigate(data string) (bool, error)
+	isNavigable() bool
+}
+
+// pagedKeyboard splits the buttons into pages of keyboardPageRows rows. The
+// controls, such as "Отмена", are shown under the buttons on every page.
+type pagedKeyboard struct {
+	buttons  []models.MarkupData
+	controls []models.MarkupData
+	columns  int
+	page     int
+}
+
+func newPagedKeyboard(req *models.SendNotificationRequest) *pagedKeyboard {
+	columns := req.Columns
+
+	if columns < 1 {
+		columns = 1
+	}
+
+	return &pagedKeyboard{buttons: req.Markup, controls: req.Controls, columns: columns}
+}
+
+func (k *pagedKeyboard) isEmpty() bool {
+	return len(k.buttons) == 0 && len(k.controls) == 0
+}
+
+func (k *pagedKeyboard) isNavigable() bool {
+	return k.pageCount() > 1
+}
+
+func (k *pagedKeyboard) navigate(data string) (bool, error) {
+	page, err := strconv.Atoi(strings.TrimPrefix(data, constants.CallbackPrefixPage))
+
+	if err != nil {
+		return false, err
+	}
+
+	page = max(0, min(page, k.pageCount()-1))
+
+	if page == k.page {
+		return false, nil
+	}
+
+	k.page = page
+	return true, nil
+}
+
+func (k *pagedKeyboard) pageSize() int {
+	return keyboardPageRows * k.columns
+}
+
+func (k *pagedKeyboard) pageCount() int {
+	if len(k.buttons) == 0 {
+		return 1
+	}
+
+	return (len(k.buttons) + k.pageSize() - 1) / k.pageSize()
+}
+
+func (k *pagedKeyboard) render() tgbotapi.InlineKeyboardMarkup {
+	from := k.page * k.pageSize()
+	to := min(from+k.pageSize(), len(k.buttons))
+
+	markup := tgbotapi.NewInlineKeyboardMarkup()
+
+	for i := from; i < to; i += k.columns {
+		var row []tgbotapi.InlineKeyboardButton
+
+		for _, v := range k.buttons[i:min(i+k.columns, to)] {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(v.Key, v.Value))
+		}
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+	}
+
+	if k.pageCount() > 1 {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, k.renderNavigation(k.page))
+	}
+
+	return appendControls(markup, k.controls)
+}
+
+func (k *pagedKeyboard) renderNavigation(page int) []tgbotapi.InlineKeyboardButton {
+	var row []tgbotapi.InlineKeyboardButton
+
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("«", pageCallbackData(page-1)))
+	}
+
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, k.pageCount()), pageCallbackData(page)))
+
+	if page < k.pageCount()-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("»", pageCallbackData(page+1)))
+	}
+
+	return row
+}
+
+func pageCallbackData(page int) string {
+	return constants.CallbackPrefixPage + strconv.Itoa(page)
+}
+
+func appendControls(markup tgbotapi.InlineKeyboardMarkup, controls []models.MarkupData) tgbotapi.InlineKeyboardMarkup {
+	for _, v := range controls {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(v.Key, v.Value)))
+	}
+
+	return markup
+}
+
+func isNavigationCallback(data string) bool {
+	return strings.HasPrefix(data, constants.CallbackPrefixPage) ||
+		strings.HasPrefix(data, constants.CallbackPrefixCalendar) ||
+		strings.HasPrefix(data, constants.CallbackPrefixTime)
+}
+
+type storedKeyboard struct {
+	messageId int
+	keyboard  inlineKeyboard
+}
+
+// keyboardStore keeps the navigable keyboards, so their navigation buttons
+// can redraw them.
+type keyboardStore struct {
+	mu    sync.Mutex
+	chats map[int64][]*storedKeyboard
+}
+
+func newKeyboardStore() *keyboardStore {
+	return &keyboardStore{chats: map[int64][]*storedKeyboard{}}
+}
+
+func (s *keyboardStore) add(chatId int64, messageId int, keyboard inlineKeyboard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyboards := append(s.chats[chatId], &storedKeyboard{messageId: messageId, keyboard: keyboard})
+
+	if len(keyboards) > maxKeyboardsPerChat {
+		keyboards = keyboards[len(keyboards)-maxKeyboardsPerChat:]
+	}
+
+	s.chats[chatId] = keyboards
+}
+
+// navigate returns the keyboard after applying the navigation button data,
+// false when there is nothing to redraw.
+func (s *keyboardStore) navigate(chatId int64, messageId int, data string) (inlineKeyboard, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range s.chats[chatId] {
+		if v.messageId != messageId {
+			continue
+		}
+
+		changed, err := v.keyboard.navigate(data)
+		return v.keyboard, changed, err
+	}
+
+	return nil, false, ErrKeyboardExpired
+}
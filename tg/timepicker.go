@@ -0,0 +1,136 @@
+package tg
+
+import (
+	"fmt"
+	"logs-aggregator-bot/constants"
+	"logs-aggregator-bot/models"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	timePickerHoursData = "hours"
+	timePickerColumns   = 6
+)
+
+// timePickerKeyboard picks a time after From and up to To, first the hour and
+// then the minute on the Step grid. The picked time is sent back as unix
+// milliseconds, To itself is always offered as a shortcut.
+type timePickerKeyboard struct {
+	from     time.Time
+	to       time.Time
+	step     time.Duration
+	hour     time.Time
+	controls []models.MarkupData
+}
+
+func newTimePickerKeyboard(req *models.SendNotificationRequest) *timePickerKeyboard {
+	keyboard := &timePickerKeyboard{
+		from:     req.TimePicker.From,
+		to:       req.TimePicker.To,
+		step:     req.TimePicker.Step,
+		controls: req.Controls,
+	}
+
+	if keyboard.step <= 0 || time.Hour%keyboard.step != 0 {
+		keyboard.step = 10 * time.Minute
+	}
+
+	if hours := keyboard.getHours(); len(hours) == 1 {
+		keyboard.hour = hours[0]
+	}
+
+	return keyboard
+}
+
+func (k *timePickerKeyboard) isNavigable() bool {
+	return true
+}
+
+func (k *timePickerKeyboard) navigate(data string) (bool, error) {
+	data = strings.TrimPrefix(data, constants.CallbackPrefixTime)
+
+	if data == timePickerHoursData {
+		changed := !k.hour.IsZero()
+		k.hour = time.Time{}
+		return changed, nil
+	}
+
+	hour, err := strconv.ParseInt(data, 10, 64)
+
+	if err != nil {
+		return false, err
+	}
+
+	if k.hour.Unix() == hour {
+		return false, nil
+	}
+
+	k.hour = time.Unix(hour, 0)
+	return true, nil
+}
+
+func (k *timePickerKeyboard) render() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+
+	if k.hour.IsZero() {
+		for _, v := range k.getHours() {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%02d ч", v.Hour()), constants.CallbackPrefixTime+strconv.FormatInt(v.Unix(), 10)))
+		}
+	} else {
+		for _, v := range k.getMinutes(k.hour) {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(v.Format("15:04"), strconv.FormatInt(v.UnixMilli(), 10)))
+		}
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup()
+
+	for i := 0; i < len(buttons); i += timePickerColumns {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, buttons[i:min(i+timePickerColumns, len(buttons))])
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+
+	if !k.hour.IsZero() && len(k.getHours()) > 1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("« Часы", constants.CallbackPrefixTime+timePickerHoursData))
+	}
+
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData("До "+k.to.Format("15:04"), strconv.FormatInt(k.to.UnixMilli(), 10)))
+	markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+
+	return appendControls(markup, k.controls)
+}
+
+// getHours returns the starts of the hours having at least one time to pick.
+func (k *timePickerKeyboard) getHours() []time.Time {
+	var hours []time.Time
+
+	for hour := getStartOfHour(k.from); !hour.After(k.to); hour = hour.Add(time.Hour) {
+		if len(k.getMinutes(hour)) > 0 {
+			hours = append(hours, hour)
+		}
+	}
+
+	return hours
+}
+
+func (k *timePickerKeyboard) getMinutes(hour time.Time) []time.Time {
+	var minutes []time.Time
+
+	for v := hour; v.Before(hour.Add(time.Hour)) && !v.After(k.to); v = v.Add(k.step) {
+		if v.After(k.from) {
+			minutes = append(minutes, v)
+		}
+	}
+
+	return minutes
+}
+
+// getStartOfHour goes by the wall clock, Truncate would be off in the time
+// zones with a fractional hour offset.
+func getStartOfHour(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), 0, 0, 0, date.Location())
+}
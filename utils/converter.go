@@ -1,6 +1,10 @@
 package utils
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 func GetOnlyTime(date time.Time) string {
 	return date.Format(time.TimeOnly)
@@ -17,20 +21,42 @@ func RoundTimeToHour(date time.Time) time.Time {
 	return date.Round(time.Hour)
 }
 
-func RoundTimeToMinutes(date time.Time) time.Time {
-	return date.Round(5 * time.Minute)
+func ParseDayTime(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
 }
 
-func GetInterval(startDate time.Time, endDate time.Time, step time.Duration) []time.Time {
-	var result []time.Time
+func ParseDayTimeRange(value string) (time.Duration, time.Duration, error) {
+	from, to, found := strings.Cut(value, "-")
 
-	for {
-		if startDate.After(endDate) {
-			break
-		}
-		result = append(result, startDate)
-		startDate = startDate.Add(step)
+	if !found {
+		return 0, 0, fmt.Errorf("expected range in format HH:MM-HH:MM, got %q", value)
 	}
 
-	return result
+	fromOffset, err := ParseDayTime(from)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	toOffset, err := ParseDayTime(to)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if fromOffset >= toOffset {
+		return 0, 0, fmt.Errorf("range start %s must be before its end %s", from, to)
+	}
+
+	return fromOffset, toOffset, nil
+}
+
+func GetStartOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 }
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const DefaultIssueKeyPattern = `\b[A-Z][A-Z0-9]+-\d+\b`
+
+// FindIssueKey returns the first issue key found in the message. Patterns are
+// tried in order, the default one is used when none are configured.
+func FindIssueKey(message string, patterns []string) string {
+	if len(patterns) == 0 {
+		patterns = []string{DefaultIssueKeyPattern}
+	}
+
+	for _, v := range patterns {
+		pattern, err := regexp.Compile(v)
+
+		if err != nil {
+			logrus.Warnf("Ignore invalid issue key pattern %q: %v", v, err)
+			continue
+		}
+
+		if key := pattern.FindString(message); key != "" {
+			return key
+		}
+	}
+
+	return ""
+}
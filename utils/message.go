@@ -0,0 +1,36 @@
+package utils
+
+import "strings"
+
+// SplitMessage cuts the text into parts of at most limit runes, preferably on
+// line breaks. Lines longer than the limit are cut as they are.
+func SplitMessage(text string, limit int) []string {
+	var parts []string
+	var part strings.Builder
+
+	partLength := 0
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		runes := []rune(line)
+
+		if partLength > 0 && partLength+len(runes) > limit {
+			parts = append(parts, strings.TrimRight(part.String(), "\n"))
+			part.Reset()
+			partLength = 0
+		}
+
+		for len(runes) > limit {
+			parts = append(parts, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+
+		part.WriteString(string(runes))
+		partLength += len(runes)
+	}
+
+	if partLength > 0 || len(parts) == 0 {
+		parts = append(parts, strings.TrimRight(part.String(), "\n"))
+	}
+
+	return parts
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "short", text: "a\nb", limit: 10, want: []string{"a\nb"}},
+		{name: "empty", text: "", limit: 10, want: []string{""}},
+		{name: "on lines", text: "aaa\nbbb\nccc", limit: 8, want: []string{"aaa\nbbb", "ccc"}},
+		{name: "long line", text: "ab\n" + strings.Repeat("я", 7), limit: 3, want: []string{"ab", "яяя", "яяя", "я"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitMessage(tt.text, tt.limit)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			for _, v := range got {
+				if len([]rune(v)) > tt.limit {
+					t.Errorf("part %q is longer than %d", v, tt.limit)
+				}
+			}
+		})
+	}
+}